@@ -0,0 +1,949 @@
+// Package asn1schema compiles a subset of ASN.1 module definitions (ITU-T X.680) and decodes BER-TLV objects
+// parsed by package bertlv against the compiled types.
+//
+// Supported are type assignments with the types BOOLEAN, INTEGER, ENUMERATED, NULL, OBJECT IDENTIFIER, RELATIVE-OID,
+// BIT STRING, OCTET STRING, the restricted character string types, UTCTime, GeneralizedTime, REAL, ANY, SEQUENCE,
+// SET, CHOICE, SEQUENCE OF and SET OF, references to other types of the module, IMPLICIT and EXPLICIT tagging,
+// OPTIONAL and DEFAULT components, extension markers and the module tag defaults EXPLICIT, IMPLICIT and AUTOMATIC.
+// Constraints are skipped, value assignments are skipped, imports are not resolved.
+package asn1schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/skythen/bertlv"
+)
+
+// Kind is the kind of an ASN.1 type.
+type Kind int
+
+const (
+	KindBoolean          Kind = iota // BOOLEAN
+	KindInteger          Kind = iota // INTEGER
+	KindEnumerated       Kind = iota // ENUMERATED
+	KindNull             Kind = iota // NULL
+	KindObjectIdentifier Kind = iota // OBJECT IDENTIFIER
+	KindRelativeOID      Kind = iota // RELATIVE-OID
+	KindBitString        Kind = iota // BIT STRING
+	KindOctetString      Kind = iota // OCTET STRING
+	KindString           Kind = iota // Restricted character string types like UTF8String, see Type.Name.
+	KindUTCTime          Kind = iota // UTCTime
+	KindGeneralizedTime  Kind = iota // GeneralizedTime
+	KindReal             Kind = iota // REAL
+	KindAny              Kind = iota // ANY
+	KindSequence         Kind = iota // SEQUENCE
+	KindSet              Kind = iota // SET
+	KindChoice           Kind = iota // CHOICE
+	KindSequenceOf       Kind = iota // SEQUENCE OF
+	KindSetOf            Kind = iota // SET OF
+	KindTagged           Kind = iota // Tagged type, e.g. [0] IMPLICIT INTEGER.
+	KindReference        Kind = iota // Reference to a type assignment of the module.
+)
+
+// Type is a compiled ASN.1 type.
+type Type struct {
+	Kind Kind
+	// Name is the keyword of built-in types, e.g. "INTEGER" or "UTF8String", and the name of the referenced
+	// type assignment for KindReference.
+	Name string
+	// Line is the line of the type in the module text.
+	Line int
+	// Tag is the universal tag of built-in types or the tag of KindTagged. Its form is not significant.
+	Tag bertlv.BerTag
+	// Explicit is true for explicitly tagged types of KindTagged.
+	Explicit bool
+	// Elem is the tagged type of KindTagged, the element type of KindSequenceOf and KindSetOf and the assigned type
+	// of KindReference.
+	Elem *Type
+	// Fields are the components of KindSequence and KindSet and the alternatives of KindChoice.
+	Fields []*Field
+	// Extensible is true if the type has an extension marker. Unknown components of extensible SEQUENCE and SET
+	// types and unknown values of extensible ENUMERATED types are accepted when decoding.
+	Extensible bool
+	// Names maps the values of the named numbers of INTEGER and ENUMERATED and the named bits of BIT STRING
+	// to their identifiers.
+	Names map[int64]string
+}
+
+// Field is a component of a SEQUENCE or SET or an alternative of a CHOICE.
+type Field struct {
+	Name     string
+	Type     *Type
+	Line     int    // Line of the component in the module text.
+	Optional bool   // Component is OPTIONAL or an extension addition.
+	Default  string // Value notation of the DEFAULT value, empty if there is none.
+}
+
+// Module is a compiled ASN.1 module.
+type Module struct {
+	Name  string // Name of the module, empty if the text has no module header.
+	types map[string]*Type
+}
+
+// Type returns the type assigned to name or nil if the module has no such type assignment.
+func (m *Module) Type(name string) *Type {
+	return m.types[name]
+}
+
+// SyntaxError is returned by Compile for module text that can not be compiled.
+type SyntaxError struct {
+	Line int    // Line in the module text.
+	Msg  string // Description of the error.
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("asn1schema: line %d: %s", e.Line, e.Msg)
+}
+
+// tagDefault is the tagging of a module that applies if neither IMPLICIT nor EXPLICIT is given.
+type tagDefault int
+
+const (
+	explicitTags  tagDefault = iota
+	implicitTags  tagDefault = iota
+	automaticTags tagDefault = iota
+)
+
+var universalTypes = map[string]struct {
+	kind   Kind
+	number uint64
+}{
+	"BOOLEAN":         {KindBoolean, 1},
+	"INTEGER":         {KindInteger, 2},
+	"NULL":            {KindNull, 5},
+	"RELATIVE-OID":    {KindRelativeOID, 13},
+	"REAL":            {KindReal, 9},
+	"UTCTime":         {KindUTCTime, 23},
+	"GeneralizedTime": {KindGeneralizedTime, 24},
+	"UTF8String":      {KindString, 12},
+	"NumericString":   {KindString, 18},
+	"PrintableString": {KindString, 19},
+	"TeletexString":   {KindString, 20},
+	"T61String":       {KindString, 20},
+	"VideotexString":  {KindString, 21},
+	"IA5String":       {KindString, 22},
+	"GraphicString":   {KindString, 25},
+	"VisibleString":   {KindString, 26},
+	"ISO646String":    {KindString, 26},
+	"GeneralString":   {KindString, 27},
+	"UniversalString": {KindString, 28},
+	"BMPString":       {KindString, 30},
+}
+
+// Compile compiles the ASN.1 module text src. The text may either be a complete module definition
+// (ModuleName DEFINITIONS ... ::= BEGIN ... END) or a sequence of assignments, which are compiled with
+// explicit tagging. Returns a *SyntaxError if the text is not supported or references undefined types.
+func Compile(src string) (*Module, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &compiler{tokens: tokens, module: &Module{types: make(map[string]*Type)}}
+
+	header := p.peekText(1) == "DEFINITIONS" || p.peekText(1) == "{"
+	if header {
+		if err = p.header(); err != nil {
+			return nil, err
+		}
+	}
+
+	for p.pos < len(p.tokens) && !(header && p.peekText(0) == "END") {
+		if err = p.assignment(); err != nil {
+			return nil, err
+		}
+	}
+
+	if header {
+		if _, err = p.expect("END"); err != nil {
+			return nil, err
+		}
+
+		if p.pos < len(p.tokens) {
+			return nil, p.errorf(p.peek(), "unexpected %q after END", p.peek().text)
+		}
+	}
+
+	if err = p.resolve(); err != nil {
+		return nil, err
+	}
+
+	return p.module, nil
+}
+
+// compiler is a recursive descent parser for ASN.1 module text.
+type compiler struct {
+	tokens     []token
+	pos        int
+	module     *Module
+	tagDefault tagDefault
+	references []*Type // Types of KindReference to resolve.
+	tagged     []*Type // Types of KindTagged.
+}
+
+func (p *compiler) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	line := 1
+	if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+
+	return token{line: line}
+}
+
+// peekText returns the text of the token k positions ahead or an empty string.
+func (p *compiler) peekText(k int) string {
+	if p.pos+k < len(p.tokens) {
+		return p.tokens[p.pos+k].text
+	}
+
+	return ""
+}
+
+func (p *compiler) next() token {
+	tok := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+
+	return tok
+}
+
+// accept consumes the next token if it has the given text.
+func (p *compiler) accept(text string) bool {
+	if p.peekText(0) == text {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *compiler) expect(text string) (token, error) {
+	tok := p.next()
+	if tok.text != text {
+		return tok, p.errorf(tok, "expected %q, got %s", text, tok.describe())
+	}
+
+	return tok, nil
+}
+
+func (p *compiler) errorf(tok token, format string, args ...interface{}) error {
+	return &SyntaxError{Line: tok.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// header parses the module header up to BEGIN and skips EXPORTS and IMPORTS.
+func (p *compiler) header() error {
+	name := p.next()
+	if !isTypeReference(name.text) {
+		return p.errorf(name, "invalid module name %s", name.describe())
+	}
+
+	p.module.Name = name.text
+
+	if p.peekText(0) == "{" {
+		if _, err := p.balanced("{", "}"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := p.expect("DEFINITIONS"); err != nil {
+		return err
+	}
+
+	if p.peekText(1) == "TAGS" {
+		switch tok := p.next(); tok.text {
+		case "EXPLICIT":
+			p.tagDefault = explicitTags
+		case "IMPLICIT":
+			p.tagDefault = implicitTags
+		case "AUTOMATIC":
+			p.tagDefault = automaticTags
+		default:
+			return p.errorf(tok, "invalid tag default %s", tok.describe())
+		}
+
+		p.next()
+	}
+
+	if p.accept("EXTENSIBILITY") {
+		if _, err := p.expect("IMPLIED"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := p.expect("::="); err != nil {
+		return err
+	}
+
+	if _, err := p.expect("BEGIN"); err != nil {
+		return err
+	}
+
+	for p.peekText(0) == "EXPORTS" || p.peekText(0) == "IMPORTS" {
+		for tok := p.next(); tok.text != ";"; tok = p.next() {
+			if tok.text == "" {
+				return p.errorf(tok, "missing \";\" after %s", "EXPORTS or IMPORTS")
+			}
+		}
+	}
+
+	return nil
+}
+
+// assignment parses a type assignment or skips a value assignment.
+func (p *compiler) assignment() error {
+	name := p.next()
+
+	switch {
+	case isTypeReference(name.text):
+		if p.peekText(0) == "{" {
+			return p.errorf(name, "parameterized type %s is not supported", name.text)
+		}
+
+		if _, err := p.expect("::="); err != nil {
+			return err
+		}
+
+		if _, ok := p.module.types[name.text]; ok {
+			return p.errorf(name, "type %s is already defined", name.text)
+		}
+
+		t, err := p.typ()
+		if err != nil {
+			return err
+		}
+
+		p.module.types[name.text] = t
+	case isIdentifier(name.text):
+		// value assignment, e.g. id-example OBJECT IDENTIFIER ::= { 1 2 3 }
+		if _, err := p.typ(); err != nil {
+			return err
+		}
+
+		if _, err := p.expect("::="); err != nil {
+			return err
+		}
+
+		if _, err := p.value(); err != nil {
+			return err
+		}
+	default:
+		return p.errorf(name, "expected an assignment, got %s", name.describe())
+	}
+
+	return nil
+}
+
+// typ parses a type followed by optional constraints.
+func (p *compiler) typ() (*Type, error) {
+	var (
+		t   *Type
+		err error
+	)
+
+	if p.peekText(0) == "[" {
+		t, err = p.taggedType()
+	} else {
+		t, err = p.builtinOrReference()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// constraints are not checked
+	for p.peekText(0) == "(" {
+		if _, err = p.balanced("(", ")"); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+func (p *compiler) taggedType() (*Type, error) {
+	start := p.next()
+
+	class := bertlv.ContextSpecific
+
+	switch p.peekText(0) {
+	case "UNIVERSAL":
+		class = bertlv.Universal
+	case "APPLICATION":
+		class = bertlv.Application
+	case "PRIVATE":
+		class = bertlv.Private
+	}
+
+	if class != bertlv.ContextSpecific {
+		p.next()
+	}
+
+	numberToken := p.next()
+
+	number, err := strconv.ParseUint(numberToken.text, 10, 64)
+	if err != nil {
+		return nil, p.errorf(numberToken, "invalid tag number %s", numberToken.describe())
+	}
+
+	tag := bertlv.NewTag(class, false, number)
+	if err = tag.CheckEncoding(); err != nil {
+		return nil, p.errorf(numberToken, "tag number %d is not supported: %s", number, err)
+	}
+
+	if _, err = p.expect("]"); err != nil {
+		return nil, err
+	}
+
+	explicit := p.tagDefault == explicitTags
+
+	switch {
+	case p.accept("IMPLICIT"):
+		explicit = false
+	case p.accept("EXPLICIT"):
+		explicit = true
+	}
+
+	elem, err := p.typ()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Type{Kind: KindTagged, Line: start.line, Tag: tag, Explicit: explicit, Elem: elem}
+	p.tagged = append(p.tagged, t)
+
+	return t, nil
+}
+
+func (p *compiler) builtinOrReference() (*Type, error) {
+	tok := p.next()
+	t := &Type{Name: tok.text, Line: tok.line}
+
+	if u, ok := universalTypes[tok.text]; ok {
+		t.Kind = u.kind
+		t.Tag = bertlv.NewTag(bertlv.Universal, false, u.number)
+
+		if t.Kind == KindInteger && p.peekText(0) == "{" {
+			var err error
+			if t.Names, _, err = p.namedNumbers(false); err != nil {
+				return nil, err
+			}
+		}
+
+		return t, nil
+	}
+
+	var err error
+
+	switch tok.text {
+	case "ENUMERATED":
+		t.Kind = KindEnumerated
+		t.Tag = bertlv.NewTag(bertlv.Universal, false, 10)
+		t.Names, t.Extensible, err = p.namedNumbers(true)
+	case "OBJECT":
+		t.Kind, t.Name = KindObjectIdentifier, "OBJECT IDENTIFIER"
+		t.Tag = bertlv.NewTag(bertlv.Universal, false, 6)
+		_, err = p.expect("IDENTIFIER")
+	case "BIT":
+		t.Kind, t.Name = KindBitString, "BIT STRING"
+		t.Tag = bertlv.NewTag(bertlv.Universal, false, 3)
+
+		if _, err = p.expect("STRING"); err == nil && p.peekText(0) == "{" {
+			t.Names, _, err = p.namedNumbers(false)
+		}
+	case "OCTET":
+		t.Kind, t.Name = KindOctetString, "OCTET STRING"
+		t.Tag = bertlv.NewTag(bertlv.Universal, false, 4)
+		_, err = p.expect("STRING")
+	case "ANY":
+		t.Kind = KindAny
+
+		if p.accept("DEFINED") {
+			if _, err = p.expect("BY"); err == nil {
+				p.next()
+			}
+		}
+	case "SEQUENCE", "SET":
+		err = p.structured(t)
+	case "CHOICE":
+		t.Kind = KindChoice
+		t.Fields, t.Extensible, err = p.components(true)
+	default:
+		if !isTypeReference(tok.text) {
+			return nil, p.errorf(tok, "expected a type, got %s", tok.describe())
+		}
+
+		if p.peekText(0) == "." || p.peekText(0) == "{" {
+			return nil, p.errorf(tok, "external or parameterized reference %s is not supported", tok.text)
+		}
+
+		t.Kind = KindReference
+		p.references = append(p.references, t)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// structured parses SEQUENCE, SET, SEQUENCE OF and SET OF after the keyword.
+func (p *compiler) structured(t *Type) error {
+	number := uint64(16)
+	if t.Name == "SET" {
+		number = 17
+	}
+
+	t.Tag = bertlv.NewTag(bertlv.Universal, true, number)
+
+	if p.peekText(0) == "{" {
+		t.Kind = KindSequence
+		if number == 17 {
+			t.Kind = KindSet
+		}
+
+		var err error
+		t.Fields, t.Extensible, err = p.components(false)
+
+		return err
+	}
+
+	// SEQUENCE SIZE (1..MAX) OF or SEQUENCE (SIZE (1..MAX)) OF
+	p.accept("SIZE")
+
+	if p.peekText(0) == "(" {
+		if _, err := p.balanced("(", ")"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := p.expect("OF"); err != nil {
+		return err
+	}
+
+	// the element may be named, e.g. SEQUENCE OF extension Extension
+	if isIdentifier(p.peekText(0)) {
+		p.next()
+	}
+
+	elem, err := p.typ()
+	if err != nil {
+		return err
+	}
+
+	t.Kind, t.Name, t.Elem = KindSequenceOf, t.Name+" OF", elem
+	if number == 17 {
+		t.Kind = KindSetOf
+	}
+
+	return nil
+}
+
+// components parses the components of SEQUENCE and SET or the alternatives of CHOICE in braces.
+// It returns the fields and whether there is an extension marker.
+func (p *compiler) components(choice bool) ([]*Field, bool, error) {
+	if _, err := p.expect("{"); err != nil {
+		return nil, false, err
+	}
+
+	var (
+		fields     []*Field
+		extensible bool
+		additions  bool // between the extension marker and the optional second ellipsis
+	)
+
+	for !p.accept("}") {
+		tok := p.peek()
+
+		switch {
+		case tok.text == "...":
+			p.next()
+
+			// the components after a second ellipsis belong to the root again
+			additions = !extensible
+			extensible = true
+
+			// exception specification
+			if p.accept("!") {
+				p.next()
+			}
+		case isIdentifier(tok.text):
+			p.next()
+
+			t, err := p.typ()
+			if err != nil {
+				return nil, false, err
+			}
+
+			// extension additions are absent in encodings of the root version
+			f := &Field{Name: tok.text, Type: t, Line: tok.line, Optional: additions}
+
+			if !choice {
+				switch {
+				case p.accept("OPTIONAL"):
+					f.Optional = true
+				case p.accept("DEFAULT"):
+					if f.Default, err = p.value(); err != nil {
+						return nil, false, err
+					}
+				}
+			}
+
+			fields = append(fields, f)
+		default:
+			return nil, false, p.errorf(tok, "expected a component, got %s", tok.describe())
+		}
+
+		if !p.accept(",") && p.peekText(0) != "}" {
+			return nil, false, p.errorf(p.peek(), "expected \",\" or \"}\", got %s", p.peek().describe())
+		}
+	}
+
+	if p.tagDefault == automaticTags {
+		p.automaticTags(fields)
+	}
+
+	return fields, extensible, nil
+}
+
+// automaticTags tags the components with [0], [1], ... if none of them is tagged.
+func (p *compiler) automaticTags(fields []*Field) {
+	for _, f := range fields {
+		if f.Type.Kind == KindTagged {
+			return
+		}
+	}
+
+	for i, f := range fields {
+		t := &Type{Kind: KindTagged, Line: f.Line, Tag: bertlv.NewTag(bertlv.ContextSpecific, false, uint64(i)), Elem: f.Type}
+		p.tagged = append(p.tagged, t)
+		f.Type = t
+	}
+}
+
+// namedNumbers parses named numbers like { v1(0), v2(1) } of INTEGER and BIT STRING or the items of ENUMERATED,
+// whose numbers are optional. It returns the names by number and whether there is an extension marker.
+func (p *compiler) namedNumbers(enumerated bool) (map[int64]string, bool, error) {
+	if _, err := p.expect("{"); err != nil {
+		return nil, false, err
+	}
+
+	names := make(map[int64]string)
+
+	var (
+		unnumbered []string
+		extensible bool
+	)
+
+	for !p.accept("}") {
+		tok := p.next()
+
+		switch {
+		case tok.text == "..." && enumerated:
+			extensible = true
+		case isIdentifier(tok.text):
+			if !p.accept("(") {
+				if !enumerated {
+					return nil, false, p.errorf(tok, "missing number of %s", tok.text)
+				}
+
+				unnumbered = append(unnumbered, tok.text)
+
+				break
+			}
+
+			number, err := p.number()
+			if err != nil {
+				return nil, false, err
+			}
+
+			if _, ok := names[number]; ok {
+				return nil, false, p.errorf(tok, "number %d of %s is already used", number, tok.text)
+			}
+
+			names[number] = tok.text
+
+			if _, err = p.expect(")"); err != nil {
+				return nil, false, err
+			}
+		default:
+			return nil, false, p.errorf(tok, "expected a named number, got %s", tok.describe())
+		}
+
+		if !p.accept(",") && p.peekText(0) != "}" {
+			return nil, false, p.errorf(p.peek(), "expected \",\" or \"}\", got %s", p.peek().describe())
+		}
+	}
+
+	// items without number get the smallest numbers that are not used
+	var next int64
+	for _, name := range unnumbered {
+		for _, ok := names[next]; ok; _, ok = names[next] {
+			next++
+		}
+
+		names[next] = name
+	}
+
+	return names, extensible, nil
+}
+
+// number parses an optionally negative number.
+func (p *compiler) number() (int64, error) {
+	sign := ""
+	if p.accept("-") {
+		sign = "-"
+	}
+
+	tok := p.next()
+
+	n, err := strconv.ParseInt(sign+tok.text, 10, 64)
+	if err != nil {
+		return 0, p.errorf(tok, "expected a number, got %s", tok.describe())
+	}
+
+	return n, nil
+}
+
+// value parses a value and returns its notation, e.g. "v1", "-1" or "{ 1 2 3 }".
+func (p *compiler) value() (string, error) {
+	switch tok := p.peek(); {
+	case tok.text == "{":
+		return p.balanced("{", "}")
+	case tok.text == "-":
+		n, err := p.number()
+		return strconv.FormatInt(n, 10), err
+	case tok.text == "" || strings.Contains(",}]);", tok.text) || tok.text == "::=":
+		return "", p.errorf(tok, "expected a value, got %s", tok.describe())
+	default:
+		p.next()
+		return tok.text, nil
+	}
+}
+
+// balanced consumes tokens from open up to the matching close and returns them separated by spaces.
+func (p *compiler) balanced(open string, close string) (string, error) {
+	start, err := p.expect(open)
+	if err != nil {
+		return "", err
+	}
+
+	texts := []string{open}
+
+	for depth := 1; depth > 0; {
+		tok := p.next()
+
+		switch tok.text {
+		case "":
+			return "", p.errorf(start, "missing %q", close)
+		case open:
+			depth++
+		case close:
+			depth--
+		}
+
+		texts = append(texts, tok.text)
+	}
+
+	return strings.Join(texts, " "), nil
+}
+
+// resolve resolves the references and applies the tagging rules that depend on referenced types.
+func (p *compiler) resolve() error {
+	for _, ref := range p.references {
+		t, ok := p.module.types[ref.Name]
+		if !ok {
+			return &SyntaxError{Line: ref.Line, Msg: fmt.Sprintf("undefined type %s", ref.Name)}
+		}
+
+		ref.Elem = t
+	}
+
+	for _, ref := range p.references {
+		t := ref
+
+		for i := 0; t.Kind == KindReference; i++ {
+			if i > len(p.references) {
+				return &SyntaxError{Line: ref.Line, Msg: fmt.Sprintf("circular definition of %s", ref.Name)}
+			}
+
+			t = t.Elem
+		}
+	}
+
+	// tags of CHOICE and ANY types are always explicit
+	for _, t := range p.tagged {
+		if k := underlying(t.Elem).Kind; k == KindChoice || k == KindAny {
+			t.Explicit = true
+		}
+	}
+
+	// the tags of values of a type that contains itself, e.g. as untagged alternative of a CHOICE, can not be matched
+	for _, ref := range p.references {
+		if containsUntagged(ref.Elem, ref.Elem, make(map[*Type]bool)) {
+			return &SyntaxError{Line: ref.Line, Msg: fmt.Sprintf("circular definition of %s without an explicit tag or a constructed type in between", ref.Name)}
+		}
+	}
+
+	return nil
+}
+
+// containsUntagged returns true if target is reachable from t through references, alternatives of CHOICE types and
+// implicit tags, which are followed when matching tags.
+func containsUntagged(t *Type, target *Type, visited map[*Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+
+	visited[t] = true
+
+	var next []*Type
+
+	switch t.Kind {
+	case KindReference:
+		next = []*Type{t.Elem}
+	case KindChoice:
+		for _, f := range t.Fields {
+			next = append(next, f.Type)
+		}
+	case KindTagged:
+		if !t.Explicit {
+			next = []*Type{t.Elem}
+		}
+	}
+
+	for _, n := range next {
+		if n == target || containsUntagged(n, target, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// underlying returns the type that t refers to.
+func underlying(t *Type) *Type {
+	for t.Kind == KindReference {
+		t = t.Elem
+	}
+
+	return t
+}
+
+// token is a lexical item of ASN.1 module text.
+type token struct {
+	text string
+	line int
+}
+
+func (t token) describe() string {
+	if t.text == "" {
+		return "end of text"
+	}
+
+	return strconv.Quote(t.text)
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+
+	line := 1
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+
+		switch {
+		case c == '\n':
+			line++
+			i++
+
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+
+			continue
+		case strings.HasPrefix(src[i:], "--"):
+			// comments end at the end of the line or with another "--"
+			i += 2
+			for i < len(src) && src[i] != '\n' && !strings.HasPrefix(src[i:], "--") {
+				i++
+			}
+
+			if strings.HasPrefix(src[i:], "--") {
+				i += 2
+			}
+
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, &SyntaxError{Line: line, Msg: "unterminated comment"}
+			}
+
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+
+			continue
+		case strings.HasPrefix(src[i:], "::="), strings.HasPrefix(src[i:], "..."):
+			i += 3
+		case strings.HasPrefix(src[i:], ".."):
+			i += 2
+		case isLetter(c):
+			for i++; i < len(src) && (isLetter(src[i]) || isDigit(src[i]) || src[i] == '-'); i++ {
+				if src[i] == '-' && (i+1 == len(src) || src[i+1] == '-') {
+					break
+				}
+			}
+		case isDigit(c):
+			for i++; i < len(src) && isDigit(src[i]); i++ {
+			}
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, &SyntaxError{Line: line, Msg: "unterminated string"}
+			}
+
+			i += end + 2
+
+			// binary and hexadecimal strings, e.g. '0A'H
+			if c == '\'' && i < len(src) && (src[i] == 'B' || src[i] == 'H') {
+				i++
+			}
+		case strings.IndexByte("{}[]()<>,;|-:.@!^&*", c) >= 0:
+			i++
+		default:
+			return nil, &SyntaxError{Line: line, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+
+		tokens = append(tokens, token{text: src[start:i], line: line})
+		line += strings.Count(src[start:i], "\n")
+	}
+
+	return tokens, nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isTypeReference returns true for type references, which start with an upper-case letter.
+func isTypeReference(s string) bool {
+	return s != "" && s[0] >= 'A' && s[0] <= 'Z'
+}
+
+// isIdentifier returns true for identifiers of components and values, which start with a lower-case letter.
+func isIdentifier(s string) bool {
+	return s != "" && s[0] >= 'a' && s[0] <= 'z'
+}
@@ -0,0 +1,230 @@
+package asn1schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/skythen/bertlv"
+)
+
+const cardModule = `CardModule { 1 2 3 } DEFINITIONS IMPLICIT TAGS ::= BEGIN
+IMPORTS Unused FROM Other;
+
+-- card data object
+CardData ::= [APPLICATION 1] SEQUENCE {
+    version  INTEGER { v1(0), v2(1) } DEFAULT v1,
+    holder   Holder,
+    keys     [1] SEQUENCE SIZE (1..8) OF KeyInfo,
+    status   Status OPTIONAL,
+    flags    [2] BIT STRING { active(0), locked(1) } OPTIONAL,
+    issued   [3] EXPLICIT GeneralizedTime OPTIONAL,
+    ...
+}
+
+Holder ::= CHOICE {
+    name [0] UTF8String (SIZE (1..64)),
+    id   [1] EXPLICIT INTEGER
+}
+
+/* key references
+   of the card */
+KeyInfo ::= SEQUENCE {
+    algorithm OBJECT IDENTIFIER,
+    keyRef    OCTET STRING (SIZE (1))
+}
+
+Status ::= ENUMERATED { active, blocked(5), terminated, ... }
+
+Attributes ::= SET {
+    label [0] IA5String,
+    usage [1] INTEGER OPTIONAL
+}
+
+Tagged ::= [PRIVATE 5] Holder
+
+id-card OBJECT IDENTIFIER ::= { iso(1) member-body(2) 3 }
+END
+`
+
+func TestCompile(t *testing.T) {
+	m, err := Compile(cardModule)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	if m.Name != "CardModule" {
+		t.Errorf("Expected: 'CardModule', got: '%s'", m.Name)
+	}
+
+	cardData := m.Type("CardData")
+	if cardData == nil || cardData.Kind != KindTagged || cardData.Explicit || cardData.Line != 5 {
+		t.Fatalf("Expected: implicitly tagged type in line 5, got: %+v", cardData)
+	}
+
+	sequence := cardData.Elem
+	if sequence.Kind != KindSequence || !sequence.Extensible || len(sequence.Fields) != 6 {
+		t.Fatalf("Expected: extensible SEQUENCE with 6 components, got: %+v", sequence)
+	}
+
+	version := sequence.Fields[0]
+	if version.Default != "v1" || version.Line != 6 || version.Type.Names[1] != "v2" {
+		t.Errorf("Expected: version DEFAULT v1 in line 6, got: %+v", version)
+	}
+
+	if keys := sequence.Fields[2].Type; keys.Kind != KindTagged || keys.Elem.Kind != KindSequenceOf || keys.Elem.Elem.Name != "KeyInfo" {
+		t.Errorf("Expected: [1] SEQUENCE OF KeyInfo, got: %+v", keys)
+	}
+
+	if issued := sequence.Fields[5].Type; !issued.Explicit || !sequence.Fields[5].Optional {
+		t.Errorf("Expected: explicitly tagged OPTIONAL component, got: %+v", issued)
+	}
+
+	expectedNames := map[int64]string{0: "active", 5: "blocked", 1: "terminated"}
+	if diff := cmp.Diff(expectedNames, m.Type("Status").Names); diff != "" {
+		t.Errorf("Mismatch (-want +got):\n%s", diff)
+	}
+
+	if m.Type("KeyInfo").Line != 22 {
+		t.Errorf("Expected: line 22, got: %d", m.Type("KeyInfo").Line)
+	}
+
+	// tags of CHOICE are always explicit
+	if tagged := m.Type("Tagged"); !tagged.Explicit {
+		t.Errorf("Expected: explicit tag, got: %+v", tagged)
+	}
+
+	if m.Type("id-card") != nil {
+		t.Errorf("Expected: value assignment to be skipped")
+	}
+}
+
+func TestCompile_ExtensionAdditions(t *testing.T) {
+	m, err := Compile(`T ::= SEQUENCE { a INTEGER, ..., b INTEGER, ..., c INTEGER }`)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	var optional []bool
+	for _, f := range m.Type("T").Fields {
+		optional = append(optional, f.Optional)
+	}
+
+	// only the extension addition b between the ellipses is optional
+	if diff := cmp.Diff([]bool{false, true, false}, optional); diff != "" {
+		t.Errorf("Mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompile_Recursion(t *testing.T) {
+	// recursion through explicit tags and constructed types can be decoded
+	if _, err := Compile("C ::= CHOICE { a INTEGER, b [0] C, c SEQUENCE OF C }"); err != nil {
+		t.Errorf("Expected: no error, got: error(%v)", err.Error())
+	}
+}
+
+func TestCompile_TagDefaults(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		expectedExplicit bool
+		expectedTags     []bertlv.BerTag
+	}{
+		{
+			name:             "no header",
+			input:            `T ::= SEQUENCE { a [0] INTEGER, b [1] IMPLICIT INTEGER }`,
+			expectedExplicit: true,
+		},
+		{
+			name:             "explicit",
+			input:            `M DEFINITIONS EXPLICIT TAGS ::= BEGIN T ::= SEQUENCE { a [0] INTEGER, b [1] IMPLICIT INTEGER } END`,
+			expectedExplicit: true,
+		},
+		{
+			name:  "implicit",
+			input: `M DEFINITIONS IMPLICIT TAGS ::= BEGIN T ::= SEQUENCE { a [0] INTEGER, b [1] INTEGER } END`,
+		},
+		{
+			name:         "automatic",
+			input:        `M DEFINITIONS AUTOMATIC TAGS ::= BEGIN T ::= SEQUENCE { a INTEGER, b INTEGER } END`,
+			expectedTags: []bertlv.BerTag{{0x80}, {0x81}},
+		},
+		{
+			name:             "automatic, tagged components",
+			input:            `M DEFINITIONS AUTOMATIC TAGS ::= BEGIN T ::= SEQUENCE { a [5] EXPLICIT INTEGER, b [1] INTEGER } END`,
+			expectedExplicit: true,
+			expectedTags:     []bertlv.BerTag{{0x85}, {0x81}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := Compile(tc.input)
+			if err != nil {
+				t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+			}
+
+			fields := m.Type("T").Fields
+
+			if fields[0].Type.Kind != KindTagged || fields[0].Type.Explicit != tc.expectedExplicit {
+				t.Errorf("Expected: explicit %v, got: %+v", tc.expectedExplicit, fields[0].Type)
+			}
+
+			if fields[1].Type.Kind != KindTagged || fields[1].Type.Explicit {
+				t.Errorf("Expected: implicit tag, got: %+v", fields[1].Type)
+			}
+
+			for i, expected := range tc.expectedTags {
+				if diff := cmp.Diff(expected, fields[i].Type.Tag); diff != "" {
+					t.Errorf("Mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedLine int
+	}{
+		{name: "undefined type", input: "A ::= SEQUENCE {\n a B\n}", expectedLine: 2},
+		{name: "circular definition", input: "A ::= B\nB ::= A", expectedLine: 1},
+		{name: "choice contains itself", input: "C ::= CHOICE {\n a INTEGER,\n c C\n}", expectedLine: 3},
+		{name: "choice contains itself through reference", input: "C ::= CHOICE { a INTEGER, d D }\nD ::= C", expectedLine: 1},
+		{name: "implicit tag of itself", input: "A ::= [0] IMPLICIT B\nB ::= [1] IMPLICIT A", expectedLine: 1},
+		{name: "duplicate type", input: "A ::= INTEGER\nA ::= BOOLEAN", expectedLine: 2},
+		{name: "missing END", input: "M DEFINITIONS ::= BEGIN\nA ::= INTEGER", expectedLine: 2},
+		{name: "text after END", input: "M DEFINITIONS ::= BEGIN END\nA ::= INTEGER", expectedLine: 2},
+		{name: "missing type", input: "A ::= ", expectedLine: 1},
+		{name: "invalid component", input: "A ::= SEQUENCE {\n\n B INTEGER }", expectedLine: 3},
+		{name: "missing comma", input: "A ::= SEQUENCE { a INTEGER b INTEGER }", expectedLine: 1},
+		{name: "invalid tag class", input: "A ::= [CONTEXT 1] INTEGER", expectedLine: 1},
+		{name: "tag number too large", input: "A ::= [16384] INTEGER", expectedLine: 1},
+		{name: "duplicate number", input: "A ::= ENUMERATED { a(1), b(1) }", expectedLine: 1},
+		{name: "missing number", input: "A ::= INTEGER { a }", expectedLine: 1},
+		{name: "parameterized type", input: "A {T} ::= SEQUENCE { a T }", expectedLine: 1},
+		{name: "external reference", input: "A ::= Other.B", expectedLine: 1},
+		{name: "unterminated comment", input: "A ::= INTEGER\n/* comment", expectedLine: 2},
+		{name: "unterminated string", input: "a UTF8String ::= \"abc", expectedLine: 1},
+		{name: "unexpected character", input: "A ::= INTEGER\n#", expectedLine: 2},
+		{name: "missing value", input: "A ::= SEQUENCE { a INTEGER DEFAULT }", expectedLine: 1},
+		{name: "unbalanced constraint", input: "A ::= INTEGER (0..", expectedLine: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Compile(tc.input)
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected: *SyntaxError, got: '%v'", err)
+			}
+
+			if syntaxErr.Line != tc.expectedLine {
+				t.Errorf("Expected: line %d, got: %d (%v)", tc.expectedLine, syntaxErr.Line, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,471 @@
+package asn1schema
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/skythen/bertlv"
+)
+
+// Value is a node of the value tree returned by Module.Decode.
+type Value struct {
+	// Name is the name of the component or CHOICE alternative. It is empty for the decoded type itself and for
+	// elements of SEQUENCE OF and SET OF.
+	Name string
+	// Type is the definition of the value as written in the module, e.g. a reference or a tagged type.
+	Type *Type
+	// TLV is the decoded object. For explicitly tagged types, it is the object with the outer tag.
+	TLV bertlv.BerTLV
+	// Content is the decoded content of values with a simple type:
+	//
+	//	BOOLEAN                      bool
+	//	INTEGER                      *big.Int
+	//	ENUMERATED                   string (identifier of the item, decimal number for unknown items of extensible types)
+	//	OBJECT IDENTIFIER            asn1.ObjectIdentifier
+	//	RELATIVE-OID                 []int
+	//	BIT STRING                   asn1.BitString
+	//	OCTET STRING                 []byte
+	//	character string types       string
+	//	UTCTime, GeneralizedTime     time.Time
+	//	REAL                         float64
+	//
+	// Content is nil for NULL, ANY and structured types.
+	Content interface{}
+	// Children are the present components of SEQUENCE and SET in the order of their definition,
+	// the elements of SEQUENCE OF and SET OF and the chosen alternative of CHOICE.
+	Children []*Value
+
+	base int // offset of the input the TLV was parsed from, see offset
+}
+
+// Field returns the child with the given name or nil if there is none, e.g. an absent OPTIONAL component.
+func (v *Value) Field(name string) *Value {
+	for _, child := range v.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// Offset returns the offset of the decoded object in the parsed input or -1 if the BerTLV was not parsed.
+func (v *Value) Offset() int {
+	return offset(v.TLV, v.base)
+}
+
+// Error is returned by Module.Decode if the BerTLV does not match the type.
+type Error struct {
+	Path   string // Path of the definition, e.g. "Certificate.tbsCertificate.serialNumber" or "Names[2]".
+	Line   int    // Line of the definition in the module text.
+	Offset int    // Offset of the object in the parsed input, -1 if unknown.
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("asn1schema: %s (line %d) at offset %d: %s", e.Path, e.Line, e.Offset, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Decode validates tlv against the type assignment with the given name and returns the decoded value tree.
+// Returns an *Error that points at the definition and the offset of the offending object if tlv does not match.
+func (m *Module) Decode(typeName string, tlv bertlv.BerTLV) (*Value, error) {
+	t := m.types[typeName]
+	if t == nil {
+		return nil, errors.Errorf("asn1schema: undefined type %s", typeName)
+	}
+
+	return decode(t, tlv, 0, typeName)
+}
+
+// DecodeBytes parses b, which must contain exactly one BER-TLV object, and decodes it like Decode.
+func (m *Module) DecodeBytes(typeName string, b []byte) (*Value, error) {
+	tlv, err := bertlv.ParseRawContent(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "asn1schema")
+	}
+
+	return m.Decode(typeName, tlv)
+}
+
+func decode(t *Type, tlv bertlv.BerTLV, base int, path string) (*Value, error) {
+	if !matchTag(t, tlv.Tag) {
+		return nil, newError(t, path, tlv, base, "expected %s, got tag %s", describe(t), tlv.Tag)
+	}
+
+	v := &Value{Type: t, TLV: tlv, base: base}
+
+	return v, decodeContent(v, t, tlv, base, path)
+}
+
+// decodeContent decodes tlv, whose tag has already been matched, into v.
+func decodeContent(v *Value, t *Type, tlv bertlv.BerTLV, base int, path string) error {
+	var err error
+
+	switch t.Kind {
+	case KindReference:
+		return decodeContent(v, t.Elem, tlv, base, path)
+	case KindTagged:
+		if !t.Explicit {
+			return decodeContent(v, t.Elem, tlv, base, path)
+		}
+
+		children, childBase, err := childrenOf(t, tlv, base, path)
+		if err != nil {
+			return err
+		}
+
+		if len(children) != 1 {
+			return newError(t, path, tlv, base, "explicitly tagged value must contain exactly one object, got %d", len(children))
+		}
+
+		inner, err := decode(t.Elem, children[0], childBase, path)
+		if err != nil {
+			return err
+		}
+
+		v.Content, v.Children = inner.Content, inner.Children
+	case KindChoice:
+		for _, f := range t.Fields {
+			if matchTag(f.Type, tlv.Tag) {
+				alternative, err := decode(f.Type, tlv, base, path+"."+f.Name)
+				if err != nil {
+					return err
+				}
+
+				alternative.Name = f.Name
+				v.Children = []*Value{alternative}
+
+				return nil
+			}
+		}
+
+		return newError(t, path, tlv, base, "no alternative of CHOICE matches tag %s", tlv.Tag)
+	case KindAny, KindNull:
+		if t.Kind == KindNull {
+			err = tlv.Null()
+		}
+	case KindSequence:
+		return decodeSequence(v, t, tlv, base, path)
+	case KindSet:
+		return decodeSet(v, t, tlv, base, path)
+	case KindSequenceOf, KindSetOf:
+		children, childBase, err := childrenOf(t, tlv, base, path)
+		if err != nil {
+			return err
+		}
+
+		for i, child := range children {
+			elem, err := decode(t.Elem, child, childBase, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return err
+			}
+
+			v.Children = append(v.Children, elem)
+		}
+	default:
+		v.Content, err = decodePrimitive(t, tlv)
+	}
+
+	if err != nil {
+		return &Error{Path: path, Line: t.Line, Offset: offset(tlv, base), Err: err}
+	}
+
+	return nil
+}
+
+func decodeSequence(v *Value, t *Type, tlv bertlv.BerTLV, base int, path string) error {
+	children, childBase, err := childrenOf(t, tlv, base, path)
+	if err != nil {
+		return err
+	}
+
+	i := 0
+
+	for _, f := range t.Fields {
+		if i < len(children) && matchTag(f.Type, children[i].Tag) {
+			child, err := decode(f.Type, children[i], childBase, path+"."+f.Name)
+			if err != nil {
+				return err
+			}
+
+			child.Name = f.Name
+			v.Children = append(v.Children, child)
+			i++
+
+			continue
+		}
+
+		if f.Optional || f.Default != "" {
+			continue
+		}
+
+		// point at the object that was found instead of the component or at the SEQUENCE
+		at, atBase := tlv, base
+		if i < len(children) {
+			at, atBase = children[i], childBase
+		}
+
+		return &Error{Path: path + "." + f.Name, Line: f.Line, Offset: offset(at, atBase), Err: errors.Errorf("missing component %s", f.Name)}
+	}
+
+	if i < len(children) && !t.Extensible {
+		return newError(t, path, children[i], childBase, "unexpected object with tag %s", children[i].Tag)
+	}
+
+	return nil
+}
+
+func decodeSet(v *Value, t *Type, tlv bertlv.BerTLV, base int, path string) error {
+	children, childBase, err := childrenOf(t, tlv, base, path)
+	if err != nil {
+		return err
+	}
+
+	decoded := make([]*Value, len(t.Fields))
+
+	for _, child := range children {
+		i := 0
+		for i < len(t.Fields) && !matchTag(t.Fields[i].Type, child.Tag) {
+			i++
+		}
+
+		if i == len(t.Fields) {
+			if t.Extensible {
+				continue
+			}
+
+			return newError(t, path, child, childBase, "unexpected object with tag %s", child.Tag)
+		}
+
+		f := t.Fields[i]
+
+		if decoded[i] != nil {
+			return &Error{Path: path + "." + f.Name, Line: f.Line, Offset: offset(child, childBase), Err: errors.Errorf("duplicate component %s", f.Name)}
+		}
+
+		if decoded[i], err = decode(f.Type, child, childBase, path+"."+f.Name); err != nil {
+			return err
+		}
+
+		decoded[i].Name = f.Name
+	}
+
+	for i, f := range t.Fields {
+		if decoded[i] != nil {
+			v.Children = append(v.Children, decoded[i])
+		} else if !f.Optional && f.Default == "" {
+			return &Error{Path: path + "." + f.Name, Line: f.Line, Offset: offset(tlv, base), Err: errors.Errorf("missing component %s", f.Name)}
+		}
+	}
+
+	return nil
+}
+
+// childrenOf returns the children of a constructed tlv and the base offset of their spans. If tlv was not parsed as
+// constructed, its value is parsed and the spans of the children are relative to the value of tlv.
+func childrenOf(t *Type, tlv bertlv.BerTLV, base int, path string) ([]bertlv.BerTLV, int, error) {
+	children := tlv.Children(nil)
+	if children != nil || len(tlv.Value) == 0 {
+		return children, base, nil
+	}
+
+	childBase := -1
+	if span, ok := tlv.Span(); ok && base >= 0 {
+		childBase = base + span.ValueOffset
+	}
+
+	children, err := bertlv.Parse(tlv.Value)
+	if err != nil {
+		return nil, childBase, &Error{Path: path, Line: t.Line, Offset: offset(tlv, base), Err: err}
+	}
+
+	return children, childBase, nil
+}
+
+func decodePrimitive(t *Type, tlv bertlv.BerTLV) (interface{}, error) {
+	// segmented strings are reassembled and decoded like primitive ones
+	if t.Kind == KindString && tlv.Tag.IsConstructed() {
+		b, err := tlv.OctetString()
+		if err != nil {
+			return nil, err
+		}
+
+		tlv = bertlv.BerTLV{Tag: bertlv.NewTag(bertlv.Universal, false, t.Tag.Number()), Value: b}
+	}
+
+	switch t.Kind {
+	case KindBoolean:
+		return tlv.Bool()
+	case KindInteger:
+		return tlv.Integer()
+	case KindEnumerated:
+		return decodeEnumerated(t, tlv)
+	case KindObjectIdentifier:
+		return tlv.ObjectIdentifier()
+	case KindRelativeOID:
+		return tlv.RelativeOID()
+	case KindBitString:
+		return tlv.BitString()
+	case KindOctetString:
+		return tlv.OctetString()
+	case KindUTCTime:
+		return tlv.UTCTime()
+	case KindGeneralizedTime:
+		return tlv.GeneralizedTime()
+	case KindReal:
+		return tlv.Real()
+	case KindString:
+		return decodeString(t, tlv)
+	}
+
+	return nil, errors.Errorf("unsupported type %s", t.Name)
+}
+
+func decodeEnumerated(t *Type, tlv bertlv.BerTLV) (interface{}, error) {
+	n, err := tlv.Enumerated()
+	if err != nil {
+		return nil, err
+	}
+
+	if name, ok := t.Names[n]; ok {
+		return name, nil
+	}
+
+	if !t.Extensible {
+		return nil, errors.Errorf("unknown value %d of ENUMERATED", n)
+	}
+
+	return fmt.Sprint(n), nil
+}
+
+func decodeString(t *Type, tlv bertlv.BerTLV) (interface{}, error) {
+	switch t.Tag.Number() {
+	case 12:
+		return tlv.UTF8String()
+	case 19:
+		return tlv.PrintableString()
+	case 22:
+		return tlv.IA5String()
+	case 30:
+		return tlv.BMPString()
+	case 18:
+		for _, c := range tlv.Value {
+			if c != ' ' && (c < '0' || c > '9') {
+				return nil, errors.Errorf("tag %02X: invalid character %q in NumericString", []byte(tlv.Tag), c)
+			}
+		}
+	case 26:
+		for _, c := range tlv.Value {
+			if c < 0x20 || c > 0x7E {
+				return nil, errors.Errorf("tag %02X: invalid character %q in VisibleString", []byte(tlv.Tag), c)
+			}
+		}
+	case 28:
+		if len(tlv.Value)%4 != 0 {
+			return nil, errors.Errorf("tag %02X: length of UniversalString must be a multiple of 4", []byte(tlv.Tag))
+		}
+
+		var sb strings.Builder
+
+		for i := 0; i < len(tlv.Value); i += 4 {
+			r := rune(tlv.Value[i])<<24 | rune(tlv.Value[i+1])<<16 | rune(tlv.Value[i+2])<<8 | rune(tlv.Value[i+3])
+			if !utf8.ValidRune(r) {
+				return nil, errors.Errorf("tag %02X: invalid character %X in UniversalString", []byte(tlv.Tag), r)
+			}
+
+			sb.WriteRune(r)
+		}
+
+		return sb.String(), nil
+	}
+
+	// the remaining types use character sets that are not decoded
+	return string(tlv.Value), nil
+}
+
+// matchTag returns true if an object with the given tag can be a value of t.
+func matchTag(t *Type, tag bertlv.BerTag) bool {
+	switch t.Kind {
+	case KindReference:
+		return matchTag(t.Elem, tag)
+	case KindAny:
+		return true
+	case KindChoice:
+		for _, f := range t.Fields {
+			if matchTag(f.Type, tag) {
+				return true
+			}
+		}
+
+		return false
+	case KindTagged:
+		if tag.Class() != t.Tag.Class() || tag.Number() != t.Tag.Number() {
+			return false
+		}
+
+		if t.Explicit {
+			return tag.IsConstructed()
+		}
+
+		return matchForm(t.Elem, tag.IsConstructed())
+	}
+
+	return tag.Class() == bertlv.Universal && tag.Number() == t.Tag.Number() && matchForm(t, tag.IsConstructed())
+}
+
+// matchForm returns true if values of t may be encoded in the given form.
+func matchForm(t *Type, constructed bool) bool {
+	switch t = underlying(t); t.Kind {
+	case KindBitString, KindOctetString, KindString, KindChoice, KindAny:
+		return true
+	case KindSequence, KindSet, KindSequenceOf, KindSetOf:
+		return constructed
+	case KindTagged:
+		if t.Explicit {
+			return constructed
+		}
+
+		return matchForm(t.Elem, constructed)
+	}
+
+	return !constructed
+}
+
+// describe returns a description of the tags that match t for error messages.
+func describe(t *Type) string {
+	switch t.Kind {
+	case KindReference:
+		return describe(t.Elem)
+	case KindChoice:
+		return "an alternative of CHOICE"
+	case KindTagged:
+		if t.Tag.Class() == bertlv.ContextSpecific {
+			return fmt.Sprintf("tag [%d]", t.Tag.Number())
+		}
+
+		return fmt.Sprintf("tag [%s %d]", t.Tag.Class(), t.Tag.Number())
+	}
+
+	return t.Name
+}
+
+func newError(t *Type, path string, tlv bertlv.BerTLV, base int, format string, args ...interface{}) *Error {
+	return &Error{Path: path, Line: t.Line, Offset: offset(tlv, base), Err: errors.Errorf(format, args...)}
+}
+
+// offset returns the offset of tlv in the input passed to Module.Decode or -1 if it is unknown.
+// base is the offset of the input tlv was parsed from, which differs from zero for re-parsed values (see childrenOf).
+func offset(tlv bertlv.BerTLV, base int) int {
+	span, ok := tlv.Span()
+	if !ok || base < 0 {
+		return -1
+	}
+
+	return base + span.Offset
+}
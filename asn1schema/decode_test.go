@@ -0,0 +1,477 @@
+package asn1schema
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/skythen/bertlv"
+)
+
+var (
+	tagApplication1 = bertlv.NewTag(bertlv.Application, true, 1)
+	tagOID          = bertlv.NewTag(bertlv.Universal, false, bertlv.UniversalObjectIdentifier)
+	tagOctetString  = bertlv.NewTag(bertlv.Universal, false, bertlv.UniversalOctetString)
+	tagSequence     = bertlv.NewTag(bertlv.Universal, true, bertlv.UniversalSequence)
+)
+
+func contextTag(constructed bool, number uint64) bertlv.BerTag {
+	return bertlv.NewTag(bertlv.ContextSpecific, constructed, number)
+}
+
+func testCardData(t *testing.T) []byte {
+	t.Helper()
+
+	issued := time.Date(2020, time.March, 4, 12, 14, 15, 0, time.UTC)
+
+	b, err := (&bertlv.Builder{}).
+		Begin(tagApplication1).
+		AddUTF8String(contextTag(false, 0), "alice").
+		Begin(contextTag(true, 1)).
+		Begin(tagSequence).
+		AddObjectIdentifier(tagOID, asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}).
+		AddOctetString(tagOctetString, []byte{0x01}).
+		End().
+		End().
+		AddEnumerated(bertlv.NewTag(bertlv.Universal, false, bertlv.UniversalEnumerated), 5).
+		Begin(contextTag(true, 3)).
+		AddGeneralizedTime(bertlv.NewTag(bertlv.Universal, false, bertlv.UniversalGeneralizedTime), issued).
+		End().
+		End().
+		BuildBerTLVs()
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	return b.Bytes()
+}
+
+func TestModule_Decode(t *testing.T) {
+	m, err := Compile(cardModule)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	v, err := m.DecodeBytes("CardData", testCardData(t))
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	var names []string
+	for _, child := range v.Children {
+		names = append(names, child.Name)
+	}
+
+	if diff := cmp.Diff([]string{"holder", "keys", "status", "issued"}, names); diff != "" {
+		t.Errorf("Mismatch (-want +got):\n%s", diff)
+	}
+
+	if v.Field("version") != nil || v.Field("flags") != nil {
+		t.Errorf("Expected: absent components to be nil")
+	}
+
+	if name := v.Field("holder").Field("name"); name == nil || name.Content != "alice" || name.Offset() != 2 {
+		t.Errorf("Expected: holder name 'alice' at offset 2, got: %+v", name)
+	}
+
+	keys := v.Field("keys").Children
+	if len(keys) != 1 || keys[0].Field("algorithm").Content.(asn1.ObjectIdentifier).String() != "1.2.840.10045.2.1" {
+		t.Errorf("Expected: one key with algorithm, got: %+v", keys)
+	}
+
+	if diff := cmp.Diff([]byte{0x01}, keys[0].Field("keyRef").Content); diff != "" {
+		t.Errorf("Mismatch (-want +got):\n%s", diff)
+	}
+
+	if status := v.Field("status").Content; status != "blocked" {
+		t.Errorf("Expected: 'blocked', got: '%v'", status)
+	}
+
+	issued := v.Field("issued")
+	if issued.TLV.Tag.Number() != 3 || !issued.Content.(time.Time).Equal(time.Date(2020, time.March, 4, 12, 14, 15, 0, time.UTC)) {
+		t.Errorf("Expected: issued time of [3], got: %+v", issued)
+	}
+}
+
+func TestModule_Decode_Types(t *testing.T) {
+	m, err := Compile(`
+Bool ::= BOOLEAN
+Int ::= [APPLICATION 2] INTEGER
+Nothing ::= NULL
+Oid ::= RELATIVE-OID
+Bits ::= BIT STRING
+Octets ::= [0] IMPLICIT OCTET STRING
+Printable ::= PrintableString
+IA5 ::= IA5String
+BMP ::= BMPString
+Numeric ::= NumericString
+Visible ::= VisibleString
+Universal ::= UniversalString
+UTC ::= UTCTime
+Float ::= REAL
+Anything ::= ANY
+Names ::= SET OF UTF8String
+Attributes ::= SET { label [0] IMPLICIT IA5String, usage [1] IMPLICIT INTEGER OPTIONAL, ... }
+Status ::= ENUMERATED { active(0), ... }
+`)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	tests := []struct {
+		name     string
+		typeName string
+		input    []byte
+		expected interface{}
+	}{
+		{name: "boolean", typeName: "Bool", input: []byte{0x01, 0x01, 0xFF}, expected: true},
+		{name: "explicit integer", typeName: "Int", input: []byte{0x62, 0x03, 0x02, 0x01, 0x2A}, expected: big.NewInt(42)},
+		{name: "null", typeName: "Nothing", input: []byte{0x05, 0x00}},
+		{name: "relative oid", typeName: "Oid", input: []byte{0x0D, 0x02, 0x01, 0x02}, expected: []int{1, 2}},
+		{name: "bit string", typeName: "Bits", input: []byte{0x03, 0x02, 0x07, 0x80}, expected: asn1.BitString{Bytes: []byte{0x80}, BitLength: 1}},
+		{name: "implicit octet string", typeName: "Octets", input: []byte{0x80, 0x01, 0xAA}, expected: []byte{0xAA}},
+		{name: "segmented octet string", typeName: "Octets", input: []byte{0xA0, 0x06, 0x04, 0x01, 0xAA, 0x04, 0x01, 0xBB}, expected: []byte{0xAA, 0xBB}},
+		{name: "printable string", typeName: "Printable", input: []byte{0x13, 0x02, 0x41, 0x42}, expected: "AB"},
+		{name: "segmented printable string", typeName: "Printable", input: []byte{0x33, 0x06, 0x04, 0x01, 0x41, 0x04, 0x01, 0x42}, expected: "AB"},
+		{name: "ia5 string", typeName: "IA5", input: []byte{0x16, 0x01, 0x40}, expected: "@"},
+		{name: "bmp string", typeName: "BMP", input: []byte{0x1E, 0x02, 0x00, 0x41}, expected: "A"},
+		{name: "numeric string", typeName: "Numeric", input: []byte{0x12, 0x02, 0x31, 0x20}, expected: "1 "},
+		{name: "visible string", typeName: "Visible", input: []byte{0x1A, 0x01, 0x7E}, expected: "~"},
+		{name: "universal string", typeName: "Universal", input: []byte{0x1C, 0x04, 0x00, 0x00, 0x00, 0x41}, expected: "A"},
+		{name: "utc time", typeName: "UTC", input: append([]byte{0x17, 0x0D}, "200304121415Z"...), expected: time.Date(2020, time.March, 4, 12, 14, 15, 0, time.UTC)},
+		{name: "real", typeName: "Float", input: []byte{0x09, 0x03, 0x80, 0xFF, 0x01}, expected: 0.5},
+		{name: "any", typeName: "Anything", input: []byte{0xDF, 0x20, 0x00}},
+		{name: "set of", typeName: "Names", input: []byte{0x31, 0x03, 0x0C, 0x01, 0x61}},
+		{name: "set, any order", typeName: "Attributes", input: []byte{0x31, 0x09, 0x81, 0x01, 0x01, 0x80, 0x01, 0x61, 0x82, 0x01, 0x00}},
+		{name: "unknown item of extensible enumerated", typeName: "Status", input: []byte{0x0A, 0x01, 0x07}, expected: "7"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := m.DecodeBytes(tc.typeName, tc.input)
+			if err != nil {
+				t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+			}
+
+			if diff := cmp.Diff(tc.expected, v.Content, cmp.Comparer(func(x, y *big.Int) bool { return x.Cmp(y) == 0 })); diff != "" {
+				t.Errorf("Mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	v, err := m.DecodeBytes("Attributes", []byte{0x31, 0x06, 0x81, 0x01, 0x01, 0x80, 0x01, 0x61})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	if len(v.Children) != 2 || v.Children[0].Name != "label" || v.Children[1].Name != "usage" {
+		t.Errorf("Expected: components in order of definition, got: %+v", v.Children)
+	}
+}
+
+func TestModule_Decode_Errors(t *testing.T) {
+	m, err := Compile(cardModule)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	valid := testCardData(t)
+
+	tests := []struct {
+		name           string
+		typeName       string
+		input          []byte
+		expectedPath   string
+		expectedLine   int
+		expectedOffset int
+	}{
+		{
+			name:           "wrong tag",
+			typeName:       "CardData",
+			input:          append([]byte{0x62}, valid[1:]...),
+			expectedPath:   "CardData",
+			expectedLine:   5,
+			expectedOffset: 0,
+		},
+		{
+			name:           "missing component",
+			typeName:       "CardData",
+			input:          []byte{0x61, 0x06, 0x80, 0x01, 0x61, 0x0A, 0x01, 0x00},
+			expectedPath:   "CardData.keys",
+			expectedLine:   8,
+			expectedOffset: 5,
+		},
+		{
+			name:           "no matching alternative",
+			typeName:       "Holder",
+			input:          []byte{0x82, 0x01, 0x01},
+			expectedPath:   "Holder",
+			expectedLine:   15,
+			expectedOffset: 0,
+		},
+		{
+			name:           "invalid content",
+			typeName:       "KeyInfo",
+			input:          []byte{0x30, 0x05, 0x06, 0x00, 0x04, 0x01, 0x01},
+			expectedPath:   "KeyInfo.algorithm",
+			expectedLine:   23,
+			expectedOffset: 2,
+		},
+		{
+			name:           "element of sequence of",
+			typeName:       "CardData",
+			input:          []byte{0x61, 0x0C, 0x80, 0x01, 0x61, 0xA1, 0x07, 0x30, 0x05, 0x06, 0x01, 0x2A, 0x05, 0x00},
+			expectedPath:   "CardData.keys[0].keyRef",
+			expectedLine:   24,
+			expectedOffset: 12,
+		},
+		{
+			name:           "unexpected component",
+			typeName:       "KeyInfo",
+			input:          []byte{0x30, 0x07, 0x06, 0x01, 0x2A, 0x04, 0x00, 0x05, 0x00},
+			expectedPath:   "KeyInfo",
+			expectedLine:   22,
+			expectedOffset: 7,
+		},
+		{
+			name:           "explicit tag without content",
+			typeName:       "Holder",
+			input:          []byte{0xA1, 0x00},
+			expectedPath:   "Holder.id",
+			expectedLine:   17,
+			expectedOffset: 0,
+		},
+		{
+			name:           "duplicate component of set",
+			typeName:       "Attributes",
+			input:          []byte{0x31, 0x06, 0x80, 0x01, 0x61, 0x80, 0x01, 0x62},
+			expectedPath:   "Attributes.label",
+			expectedLine:   30,
+			expectedOffset: 5,
+		},
+		{
+			name:           "missing component of set",
+			typeName:       "Attributes",
+			input:          []byte{0x31, 0x03, 0x81, 0x01, 0x01},
+			expectedPath:   "Attributes.label",
+			expectedLine:   30,
+			expectedOffset: 0,
+		},
+		{
+			name:           "primitive sequence",
+			typeName:       "KeyInfo",
+			input:          []byte{0x10, 0x00},
+			expectedPath:   "KeyInfo",
+			expectedLine:   22,
+			expectedOffset: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := m.DecodeBytes(tc.typeName, tc.input)
+
+			var decodeErr *Error
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("Expected: *Error, got: '%v'", err)
+			}
+
+			if decodeErr.Path != tc.expectedPath || decodeErr.Line != tc.expectedLine || decodeErr.Offset != tc.expectedOffset {
+				t.Errorf("Expected: %s (line %d) at offset %d, got: '%v'", tc.expectedPath, tc.expectedLine, tc.expectedOffset, err)
+			}
+		})
+	}
+
+	if _, err = m.Decode("Unknown", bertlv.BerTLV{}); err == nil {
+		t.Errorf("Expected: error, got: nil")
+	}
+
+	if _, err = m.DecodeBytes("KeyInfo", []byte{0x30, 0x00, 0x00}); err == nil {
+		t.Errorf("Expected: error, got: nil")
+	}
+}
+
+func TestModule_Decode_NestedImplicitTags(t *testing.T) {
+	m, err := Compile(`
+Wrapper ::= SEQUENCE {
+    value [0] IMPLICIT [1] IMPLICIT INTEGER
+}
+`)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	v, err := m.DecodeBytes("Wrapper", []byte{0x30, 0x03, 0x80, 0x01, 0x05})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	if value := v.Field("value").Content.(*big.Int); value.Int64() != 5 {
+		t.Errorf("Expected: 5, got: %v", value)
+	}
+
+	// the constructed form is not permitted for INTEGER, however many implicit tags replace its own
+	_, err = m.DecodeBytes("Wrapper", []byte{0x30, 0x05, 0xA0, 0x03, 0x02, 0x01, 0x05})
+
+	var decodeErr *Error
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected: *Error, got: '%v'", err)
+	}
+
+	if decodeErr.Path != "Wrapper.value" || decodeErr.Line != 3 || decodeErr.Offset != 2 {
+		t.Errorf("Expected: Wrapper.value (line 3) at offset 2, got: '%v'", err)
+	}
+}
+
+func TestModule_Decode_ReparsedOffsets(t *testing.T) {
+	m, err := Compile(`
+Outer ::= SEQUENCE {
+    pad   OCTET STRING,
+    inner [1] IMPLICIT SEQUENCE {
+        a INTEGER,
+        b OBJECT IDENTIFIER
+    }
+}
+`)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	// the value of [1] is not parsed as constructed and has to be parsed again while decoding
+	primitive1 := func(tag bertlv.BerTag) bertlv.Form {
+		if tag.Class() == bertlv.ContextSpecific && tag.Number() == 1 {
+			return bertlv.FormPrimitive
+		}
+
+		return bertlv.FormDefault
+	}
+
+	parse := func(b []byte) bertlv.BerTLV {
+		tlvs, err := bertlv.ParseWithOptions(b, bertlv.ParseOptions{Form: primitive1})
+		if err != nil {
+			t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+		}
+
+		return tlvs[0]
+	}
+
+	v, err := m.Decode("Outer", parse([]byte{0x30, 0x0B, 0x04, 0x01, 0x00, 0xA1, 0x06, 0x02, 0x01, 0x01, 0x06, 0x01, 0x2A}))
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	if offset := v.Field("inner").Field("b").Offset(); offset != 10 {
+		t.Errorf("Expected: offset 10, got: %d", offset)
+	}
+
+	_, err = m.Decode("Outer", parse([]byte{0x30, 0x0A, 0x04, 0x01, 0x00, 0xA1, 0x05, 0x02, 0x01, 0x01, 0x06, 0x00}))
+
+	var decodeErr *Error
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected: *Error, got: '%v'", err)
+	}
+
+	if decodeErr.Path != "Outer.inner.b" || decodeErr.Line != 6 || decodeErr.Offset != 10 {
+		t.Errorf("Expected: Outer.inner.b (line 6) at offset 10, got: '%v'", err)
+	}
+}
+
+func TestModule_Decode_Certificate(t *testing.T) {
+	m, err := Compile(`
+PKIX DEFINITIONS EXPLICIT TAGS ::= BEGIN
+Certificate ::= SEQUENCE {
+    tbsCertificate       TBSCertificate,
+    signatureAlgorithm   AlgorithmIdentifier,
+    signatureValue       BIT STRING }
+
+TBSCertificate ::= SEQUENCE {
+    version         [0]  Version DEFAULT v1,
+    serialNumber         INTEGER,
+    signature            AlgorithmIdentifier,
+    issuer               Name,
+    validity             Validity,
+    subject              Name,
+    subjectPublicKeyInfo SEQUENCE { algorithm AlgorithmIdentifier, subjectPublicKey BIT STRING },
+    issuerUniqueID  [1]  IMPLICIT BIT STRING OPTIONAL,
+    subjectUniqueID [2]  IMPLICIT BIT STRING OPTIONAL,
+    extensions      [3]  SEQUENCE SIZE (1..MAX) OF Extension OPTIONAL }
+
+Version ::= INTEGER { v1(0), v2(1), v3(2) }
+
+AlgorithmIdentifier ::= SEQUENCE {
+    algorithm  OBJECT IDENTIFIER,
+    parameters ANY DEFINED BY algorithm OPTIONAL }
+
+Name ::= CHOICE { rdnSequence SEQUENCE OF SET SIZE (1..MAX) OF AttributeTypeAndValue }
+
+AttributeTypeAndValue ::= SEQUENCE { type OBJECT IDENTIFIER, value ANY DEFINED BY type }
+
+Validity ::= SEQUENCE { notBefore Time, notAfter Time }
+
+Time ::= CHOICE { utcTime UTCTime, generalTime GeneralizedTime }
+
+Extension ::= SEQUENCE {
+    extnID    OBJECT IDENTIFIER,
+    critical  BOOLEAN DEFAULT FALSE,
+    extnValue OCTET STRING }
+END
+`)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "bertlv"},
+		NotBefore:    time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	v, err := m.DecodeBytes("Certificate", der)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	tbs := v.Field("tbsCertificate")
+
+	if version := tbs.Field("version").Content.(*big.Int); version.Int64() != 2 {
+		t.Errorf("Expected: version 2, got: %v", version)
+	}
+
+	if serial := tbs.Field("serialNumber").Content.(*big.Int); serial.Int64() != 42 {
+		t.Errorf("Expected: serial number 42, got: %v", serial)
+	}
+
+	notAfter := tbs.Field("validity").Field("notAfter").Children[0]
+	if notAfter.Name != "utcTime" || !notAfter.Content.(time.Time).Equal(template.NotAfter) {
+		t.Errorf("Expected: notAfter %v, got: %+v", template.NotAfter, notAfter)
+	}
+
+	attribute := tbs.Field("subject").Field("rdnSequence").Children[0].Children[0]
+	if value, err := attribute.Field("value").TLV.PrintableString(); err != nil || value != "bertlv" {
+		t.Errorf("Expected: 'bertlv', got: '%s', error(%v)", value, err)
+	}
+
+	if extensions := tbs.Field("extensions").Children; len(extensions) == 0 || extensions[0].Field("critical") == nil {
+		t.Errorf("Expected: critical key usage extension, got: %+v", extensions)
+	}
+}
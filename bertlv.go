@@ -6,6 +6,8 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -23,6 +25,11 @@ type BerTLV struct {
 	Tag      BerTag   // Tag of the BER-TLV structure.
 	Value    []byte   // Value of the BER-TLV structure.
 	children []BerTLV // Nested BER-TLV objects that may be contained in Value.
+	header   []byte   // Tag and length bytes as found in the parsed input, nil if the BerTLV was not parsed.
+	offset   int      // Offset of the first tag byte in the parsed input.
+	unparsed error    // Reason why Value could not be parsed, only set for pseudo-objects created by ParseLenient.
+
+	speculative []BerTLV // Objects found in the value of a primitive BER-TLV object by BerTLVs.DecodeHeuristic.
 }
 
 // BerTLVs is a slice of BerTLV.
@@ -36,16 +43,15 @@ func NewBerTLV(tag BerTag, value []byte) (*BerTLV, error) {
 		return &BerTLV{Tag: tag, Value: value}, nil
 	}
 
-	children := make([]BerTLV, 0)
+	p := parser{}
 
-	for index := 0; index < len(value); {
-		tlv, lenParsed, err := parseFirstBerTLV(value[index:])
-		if err != nil {
-			return nil, errors.Wrap(err, fmt.Sprintf("%s: tag %02X invalid content", packageTag, tag))
-		}
+	children, _, err := p.parse(value, 0, false)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("%s: tag %02X invalid content", packageTag, []byte(tag)))
+	}
 
-		children = append(children, tlv)
-		index += lenParsed
+	if children == nil {
+		children = make([]BerTLV, 0)
 	}
 
 	return &BerTLV{Tag: tag, Value: value, children: children}, nil
@@ -73,73 +79,38 @@ func NewThreeByteTag(fb byte, sb byte, tb byte) BerTag {
 }
 
 // Parse recursively parses BER-TLV encoded bytes and returns BerTLVs.
+// Use ParseWithOptions to limit the resources used for parsing untrusted input.
 func Parse(b []byte) (BerTLVs, error) {
-	if len(b) == 0 {
-		return nil, errors.Errorf("%s: TLV has length 0", packageTag)
-	}
-
-	var result []BerTLV
-
-	for index := 0; index < len(b); {
-		tlvs, lenParsed, err := parseFirstBerTLV(b[index:])
-		if err != nil {
-			return BerTLVs{}, errors.Wrap(err, fmt.Sprintf("%s: invalid TLV starting at index %d", packageTag, index))
-		}
-
-		result = append(result, tlvs)
-		index += lenParsed
-	}
+	return ParseWithOptions(b, ParseOptions{})
+}
 
-	return result, nil
+// ParseFirst parses the first BER-TLV object of b including its children and returns it together with the number of
+// bytes consumed and the remaining bytes following it.
+// Unlike Parse, ParseFirst does not fail on trailing data that is not BER-TLV encoded, e.g. status words or MACs.
+func ParseFirst(b []byte) (BerTLV, int, []byte, error) {
+	return ParseFirstWithOptions(b, ParseOptions{})
 }
 
-func parseFirstBerTLV(b []byte) (berTLV BerTLV, lenParsed int, err error) {
+// parseHeader parses the tag and length of the BER-TLV object at the start of b and checks that the indicated
+// value is contained in b. It returns the tag, the length of the value and the length of the header.
+func parseHeader(b []byte) (BerTag, int, int, error) {
 	tag, err := parseTag(b)
 	if err != nil {
-		return BerTLV{}, 0, errors.Wrap(err, fmt.Sprintf("invalid tag at start: %02X", b))
+		return nil, 0, 0, errors.Wrap(err, fmt.Sprintf("invalid tag at start: %02X", b))
 	}
 
-	leftIndex := len(tag)
-
-	length, lLen, err := parseLength(b[leftIndex:])
+	length, lLen, err := parseLength(b[len(tag):])
 	if err != nil {
-		return BerTLV{}, 0, errors.Wrap(err, fmt.Sprintf("tag %02X: invalid length encoding", tag))
+		return nil, 0, 0, errors.Wrap(err, fmt.Sprintf("tag %02X: invalid length encoding", []byte(tag)))
 	}
 
-	leftIndex += lLen
-
-	indicatedEndIndex := leftIndex + length - 1
+	hLen := len(tag) + lLen
 
-	if endIndex := len(b) - 1; indicatedEndIndex > endIndex {
-		return BerTLV{}, 0, errors.Errorf("tag %02X: indicated length of value is out of bounds - indicated end index: %d actual end index %d", tag, indicatedEndIndex, endIndex)
+	if indicatedEndIndex, endIndex := hLen+length-1, len(b)-1; indicatedEndIndex > endIndex {
+		return nil, 0, 0, errors.Errorf("tag %02X: indicated length of value is out of bounds - indicated end index: %d actual end index %d", []byte(tag), indicatedEndIndex, endIndex)
 	}
 
-	value := b[leftIndex : leftIndex+length]
-	if len(value) == 0 {
-		return BerTLV{Tag: tag}, leftIndex, nil
-	}
-
-	leftIndex += length
-
-	result := BerTLV{Tag: tag, Value: value}
-
-	if tag.IsConstructed() {
-		result.children = make([]BerTLV, 0, len(value)/2)
-
-		for valueIndex := 0; valueIndex < len(value); {
-			var child BerTLV
-
-			child, lenParsed, err = parseFirstBerTLV(value[valueIndex:])
-			if err != nil {
-				return BerTLV{}, 0, errors.Wrap(err, fmt.Sprintf("tag %02X: invalid child object", tag))
-			}
-
-			result.children = append(result.children, child)
-			valueIndex += lenParsed
-		}
-	}
-
-	return result, leftIndex, nil
+	return tag, length, hLen, nil
 }
 
 func parseTag(b []byte) (BerTag, error) {
@@ -209,11 +180,39 @@ func buildLen(l int) []byte {
 	return []byte{0x82, (byte)(l>>8) & 0xFF, (byte)(l & 0xFF)}
 }
 
+// lenOfLen returns the number of bytes of the minimal length field for a value of length l.
+func lenOfLen(l int) int {
+	switch {
+	case l <= 127:
+		return 1
+	case l <= 255:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// appendLen appends the minimal length field for a value of length l to b.
+func appendLen(b []byte, l int) []byte {
+	switch {
+	case l <= 127:
+		return append(b, byte(l))
+	case l <= 255:
+		return append(b, 0x81, byte(l))
+	default:
+		return append(b, 0x82, byte(l>>8), byte(l))
+	}
+}
+
 // CheckEncoding checks if the encoding of the BerTag - that is the indication of subsequent tag bytes - is correct.
 // If the encoding is correct, CheckEncoding returns nil, otherwise an error with details is returned.
 func (t BerTag) CheckEncoding() error {
 	l := len(t)
 
+	if l == 0 {
+		return errors.New("tag is empty")
+	}
+
 	if l > 3 {
 		return errors.Errorf("tags must consist of a maximum of three bytes, got %d", len(t))
 	}
@@ -252,6 +251,49 @@ func (t BerTag) IsConstructed() bool {
 	return t[0]&0x20 != 0
 }
 
+// EncodingMode determines how tag and length bytes are encoded by BerTLV.Encode and BerTLVs.Encode.
+type EncodingMode int
+
+const (
+	// MinimalEncoding encodes the length with the minimal number of bytes. This is the encoding used by BerTLV.Bytes.
+	MinimalEncoding EncodingMode = iota
+	// PreserveEncoding keeps the original tag and length bytes of parsed BerTLV objects, see BerTLV.RawBytes.
+	// BerTLV objects that were not parsed or that have been modified are encoded with MinimalEncoding.
+	PreserveEncoding
+	// CEREncoding encodes the tree of BerTLV objects according to the Canonical Encoding Rules of ITU-T X.690 clause 9,
+	// see BerTLV.EncodeCER. Encode returns nil if the objects can not be encoded in CER, use EncodeCER for the reason.
+	CEREncoding
+)
+
+// Form determines whether the value of a BER-TLV object is parsed as nested BER-TLV objects.
+// It is used to override the encoding of the tag for proprietary or legacy data, see ParseOptions.Form.
+type Form int
+
+const (
+	// FormDefault parses the value as nested objects if the tag indicates a constructed object (see BerTag.IsConstructed).
+	FormDefault Form = iota
+	// FormConstructed parses the value as nested objects regardless of the tag.
+	FormConstructed
+	// FormPrimitive treats the value as opaque bytes regardless of the tag.
+	FormPrimitive
+)
+
+// isConstructed returns true if the value of an object with the given tag is parsed as nested objects.
+// form may be nil.
+func isConstructed(tag BerTag, form func(tag BerTag) Form) bool {
+	if form != nil {
+		switch form(tag) {
+		case FormConstructed:
+			return true
+		case FormPrimitive:
+			return false
+		}
+	}
+
+	return tag.IsConstructed()
+}
+
+// Class is the class of a BerTag as encoded in b8 and b7 of its first byte.
 type Class int
 
 const (
@@ -261,7 +303,66 @@ const (
 	Private         Class = iota
 )
 
+// String returns the name of the Class as used in ASN.1 notation, e.g. "APPLICATION".
+// ContextSpecific has no name in ASN.1 notation and is returned as "CONTEXT".
+func (c Class) String() string {
+	switch c {
+	case Universal:
+		return "UNIVERSAL"
+	case Application:
+		return "APPLICATION"
+	case ContextSpecific:
+		return "CONTEXT"
+	case Private:
+		return "PRIVATE"
+	default:
+		return fmt.Sprintf("Class(%d)", int(c))
+	}
+}
+
+// NewTag returns a new BerTag with the given class, form and tag number using the minimal number of bytes.
+// Tag numbers up to 30 are encoded in one byte, up to 127 in two bytes and up to 16383 in three bytes.
+// Greater tag numbers result in tags with more than three bytes that are not supported by Parse
+// and rejected by BerTag.CheckEncoding.
+//
+// class must be one of Universal, Application, ContextSpecific and Private. Since the class is encoded in two bits,
+// only the two least significant bits of other values are used, e.g. Class(5) results in an Application tag.
+func NewTag(class Class, constructed bool, number uint64) BerTag {
+	first := byte(class&0x03) << 6
+	if constructed {
+		first |= 0x20
+	}
+
+	if number < 0x1F {
+		return BerTag{first | byte(number)}
+	}
+
+	tag := BerTag{first | 0x1F}
+
+	// subsequent bytes: 7 bits of the tag number each, b8 indicates that another byte follows
+	n := 1
+	for rest := number >> 7; rest > 0; rest >>= 7 {
+		n++
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		b := byte(number>>(7*uint(i))) & 0x7F
+		if i > 0 {
+			b |= 0x80
+		}
+
+		tag = append(tag, b)
+	}
+
+	return tag
+}
+
+// Class returns the Class of the BerTag. Empty tags are of class Universal.
 func (t BerTag) Class() Class {
+	if len(t) == 0 {
+		return Universal
+	}
+
 	switch t[0] & 0xC0 {
 	case 0x40:
 		return Application
@@ -274,12 +375,178 @@ func (t BerTag) Class() Class {
 	}
 }
 
+// Number returns the tag number of the BerTag, which is encoded in b5-b1 of the first byte or, if these are all set,
+// in b7-b1 of the subsequent bytes. Returns 0 for empty tags.
+func (t BerTag) Number() uint64 {
+	if len(t) == 0 {
+		return 0
+	}
+
+	if t[0]&0x1F != 0x1F {
+		return uint64(t[0] & 0x1F)
+	}
+
+	var number uint64
+
+	for _, b := range t[1:] {
+		number = number<<7 | uint64(b&0x7F)
+
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	return number
+}
+
+// String returns the BerTag in ASN.1 notation followed by its form, e.g. "[APPLICATION 15] constructed".
+// Context-specific tags are written without class, e.g. "[0] primitive". Returns an empty string for empty tags.
+func (t BerTag) String() string {
+	if len(t) == 0 {
+		return ""
+	}
+
+	form := "primitive"
+	if t.IsConstructed() {
+		form = "constructed"
+	}
+
+	if t.Class() == ContextSpecific {
+		return fmt.Sprintf("[%d] %s", t.Number(), form)
+	}
+
+	return fmt.Sprintf("[%s %d] %s", t.Class(), t.Number(), form)
+}
+
+// Format implements fmt.Formatter. The verb %s formats the BerTag like String. All other verbs format it like a
+// byte slice, so e.g. %X prints the tag bytes in hex ("5F20") and %v prints them in decimal ("[95 32]").
+func (t BerTag) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 's':
+		fmt.Fprintf(f, formatDirective(f, verb), t.String())
+	case verb == 'v' && f.Flag('#'):
+		fmt.Fprintf(f, "bertlv.BerTag%s", strings.TrimPrefix(fmt.Sprintf("%#v", []byte(t)), "[]byte"))
+	default:
+		fmt.Fprintf(f, formatDirective(f, verb), []byte(t))
+	}
+}
+
+// formatDirective returns the formatting directive with flags, width and precision of f for verb, e.g. "%02X".
+func formatDirective(f fmt.State, verb rune) string {
+	directive := []byte{'%'}
+
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			directive = append(directive, byte(flag))
+		}
+	}
+
+	if width, ok := f.Width(); ok {
+		directive = strconv.AppendInt(directive, int64(width), 10)
+	}
+
+	if precision, ok := f.Precision(); ok {
+		directive = append(directive, '.')
+		directive = strconv.AppendInt(directive, int64(precision), 10)
+	}
+
+	return string(append(directive, string(verb)...))
+}
+
 // Bytes returns BerTLVs as BER-TLV encoded bytes.
 func (t BerTLVs) Bytes() []byte {
+	if len(t) == 0 {
+		return nil
+	}
+
+	return t.AppendBytes(make([]byte, 0, t.EncodedLen()))
+}
+
+// AppendBytes appends BerTLVs as BER-TLV encoded bytes as returned by Bytes to dst and returns the extended slice.
+// If dst has sufficient capacity (see EncodedLen), no memory is allocated.
+func (t BerTLVs) AppendBytes(dst []byte) []byte {
+	for _, tlv := range t {
+		dst = tlv.AppendBytes(dst)
+	}
+
+	return dst
+}
+
+// AppendBytesChecked appends BerTLVs as BER-TLV encoded bytes to dst like AppendBytes, but returns dst unchanged
+// and an error if a BerTLV would be truncated, see BerTLV.AppendBytesChecked.
+func (t BerTLVs) AppendBytesChecked(dst []byte) ([]byte, error) {
+	for _, tlv := range t {
+		if err := tlv.checkEncodable(); err != nil {
+			return dst, err
+		}
+	}
+
+	return t.AppendBytes(dst), nil
+}
+
+// WriteTo implements io.WriterTo. It writes BerTLVs as BER-TLV encoded bytes as returned by Bytes to w
+// and returns the number of bytes written. Only one buffer for the tag and length bytes is allocated, see
+// BerTLV.WriteTo. Returns an error without writing anything if a BerTLV would be truncated.
+func (t BerTLVs) WriteTo(w io.Writer) (int64, error) {
+	for _, tlv := range t {
+		if err := tlv.checkEncodable(); err != nil {
+			return 0, err
+		}
+	}
+
+	var n int64
+
+	header := make([]byte, 0, 6)
+
+	for _, tlv := range t {
+		m, err := tlv.writeTo(w, header)
+		n += m
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// EncodedLen returns the exact length of the BER-TLV encoded bytes returned by Bytes, AppendBytes and WriteTo.
+func (t BerTLVs) EncodedLen() int {
+	n := 0
+
+	for _, tlv := range t {
+		n += tlv.EncodedLen()
+	}
+
+	return n
+}
+
+// Clone returns a deep copy of BerTLVs, see BerTLV.Clone. It returns nil if BerTLVs is nil.
+func (t BerTLVs) Clone() BerTLVs {
+	if t == nil {
+		return nil
+	}
+
+	clone := make(BerTLVs, len(t))
+	for i, tlv := range t {
+		clone[i] = tlv.Clone()
+	}
+
+	return clone
+}
+
+// Encode returns BerTLVs as BER-TLV encoded bytes using the given EncodingMode.
+// With PreserveEncoding the result reproduces the parsed input exactly unless BerTLV objects have been modified or added.
+func (t BerTLVs) Encode(mode EncodingMode) []byte {
+	if mode == CEREncoding {
+		b, _ := t.EncodeCER()
+		return b
+	}
+
 	var b []byte
 
 	for _, tlv := range t {
-		b = append(b, tlv.Bytes()...)
+		b = append(b, tlv.Encode(mode)...)
 	}
 
 	return b
@@ -324,50 +591,229 @@ func (t BerTLVs) FindFirstWithTag(tag BerTag) *BerTLV {
 
 // Bytes returns a byte slice containing the byte representation of BerTLV (Tag | Length | Value).
 // If the value of a BerTLV exceeds a length of 65535 it gets truncated.
+// Pseudo-objects for malformed input (see BerTLV.Unparsed) have no tag and length, their Value is returned unchanged.
 func (ber BerTLV) Bytes() []byte {
-	var (
-		tagLen    int
-		lengthLen int
-		valueLen  int
-		tag       []byte
-		length    []byte
-	)
+	return ber.AppendBytes(make([]byte, 0, ber.EncodedLen()))
+}
 
-	valueLen = len(ber.Value)
+// AppendBytes appends the byte representation of the BerTLV as returned by Bytes to dst and returns the extended
+// slice. If dst has sufficient capacity (see EncodedLen), no memory is allocated.
+func (ber BerTLV) AppendBytes(dst []byte) []byte {
+	dst = ber.appendHeader(dst)
 
-	if valueLen > 65535 {
-		ber.Value = ber.Value[:65535]
-		valueLen = 65535
+	return append(dst, ber.encodedValue()...)
+}
+
+// AppendBytesChecked appends the byte representation of the BerTLV to dst like AppendBytes, but returns dst
+// unchanged and an error instead of truncating a tag with more than three bytes or a value that exceeds a length
+// of 65535.
+func (ber BerTLV) AppendBytesChecked(dst []byte) ([]byte, error) {
+	if err := ber.checkEncodable(); err != nil {
+		return dst, err
 	}
 
-	length = buildLen(valueLen)
-	tagLen = len(ber.Tag)
+	return ber.AppendBytes(dst), nil
+}
 
-	if tagLen > 0 && tagLen <= 3 {
-		tag = ber.Tag
-	} else if tagLen == 0 {
-		// fill empty tag
-		tag = []byte{0x00}
+// WriteTo implements io.WriterTo. It writes the byte representation of the BerTLV as returned by Bytes to w
+// and returns the number of bytes written. The value is written without copying it, only a buffer for the tag and
+// length bytes is allocated. Unlike Bytes, it returns an error without writing anything if the tag has more than
+// three bytes or the value exceeds a length of 65535.
+func (ber BerTLV) WriteTo(w io.Writer) (int64, error) {
+	if err := ber.checkEncodable(); err != nil {
+		return 0, err
+	}
+
+	return ber.writeTo(w, make([]byte, 0, 6))
+}
+
+// writeTo writes the byte representation of the BerTLV to w using header as buffer for the tag and length bytes.
+func (ber BerTLV) writeTo(w io.Writer, header []byte) (int64, error) {
+	n, err := w.Write(ber.appendHeader(header[:0]))
+	if err != nil {
+		return int64(n), err
+	}
+
+	m, err := w.Write(ber.encodedValue())
+
+	return int64(n + m), err
+}
+
+// checkEncodable returns an error if the tag or the value of the BerTLV are truncated in its byte representation.
+func (ber BerTLV) checkEncodable() error {
+	if ber.unparsed != nil {
+		return nil
+	}
+
+	if len(ber.Tag) > 3 {
+		return errors.Errorf("%s: tag %02X: tags with more than three bytes are not supported", packageTag, []byte(ber.Tag))
+	}
+
+	if len(ber.Value) > 65535 {
+		return errors.Errorf("%s: tag %02X: length of value exceeds 65535: %d", packageTag, []byte(ber.Tag), len(ber.Value))
+	}
+
+	return nil
+}
+
+// EncodedLen returns the exact length of the byte representation returned by Bytes, AppendBytes and WriteTo.
+// Unlike BytesLength, it takes into account that empty tags are encoded as '00' and that tags with more than three
+// bytes are truncated. Use AppendBytesChecked or WriteTo to get an error instead of a truncated encoding.
+func (ber BerTLV) EncodedLen() int {
+	if ber.unparsed != nil {
+		return len(ber.Value)
+	}
+
+	tagLen := len(ber.Tag)
+
+	switch {
+	case tagLen == 0:
 		tagLen = 1
-	} else {
-		// truncate tag to three byte
+	case tagLen > 3:
 		tagLen = 3
-		tag = ber.Tag[:tagLen]
 	}
 
-	lengthLen = len(length)
+	valueLen := len(ber.encodedValue())
 
-	result := make([]byte, 0, tagLen+lengthLen+valueLen)
-	result = append(result, tag...)
-	result = append(result, length...)
+	return tagLen + lenOfLen(valueLen) + valueLen
+}
+
+// appendHeader appends the tag and length bytes of the byte representation of the BerTLV to dst.
+// Empty tags are encoded as '00' and tags with more than three bytes are truncated.
+func (ber BerTLV) appendHeader(dst []byte) []byte {
+	if ber.unparsed != nil {
+		return dst
+	}
+
+	switch tagLen := len(ber.Tag); {
+	case tagLen == 0:
+		dst = append(dst, 0x00)
+	case tagLen > 3:
+		dst = append(dst, ber.Tag[:3]...)
+	default:
+		dst = append(dst, ber.Tag...)
+	}
+
+	return appendLen(dst, len(ber.encodedValue()))
+}
+
+// encodedValue returns the Value of the BerTLV truncated to a length of 65535.
+func (ber BerTLV) encodedValue() []byte {
+	if ber.unparsed == nil && len(ber.Value) > 65535 {
+		return ber.Value[:65535]
+	}
+
+	return ber.Value
+}
+
+// RawBytes returns the byte representation of the BerTLV with the tag and length bytes exactly as they were found
+// in the input the BerTLV was parsed from, e.g. a non-minimal length encoding like '81 08' is kept.
+// Since the Value of a constructed BerTLV contains the original encoding of its children, the whole subtree
+// is reproduced byte-exact.
+//
+// Returns nil if the BerTLV was not parsed or if its Tag or the length of its Value have been changed after parsing.
+func (ber BerTLV) RawBytes() []byte {
+	if ber.unparsed != nil {
+		return append([]byte{}, ber.Value...)
+	}
+
+	if ber.header == nil {
+		return nil
+	}
+
+	tag, err := parseTag(ber.header)
+	if err != nil || !bytes.Equal(tag, ber.Tag) {
+		return nil
+	}
+
+	length, _, err := parseLength(ber.header[len(tag):])
+	if err != nil || length != len(ber.Value) {
+		return nil
+	}
+
+	result := make([]byte, 0, len(ber.header)+len(ber.Value))
+	result = append(result, ber.header...)
 	result = append(result, ber.Value...)
 
 	return result
 }
 
+// Span describes the location of a parsed BerTLV in the input it was parsed from.
+// All offsets are absolute positions in the input that was passed to Parse.
+// For child objects of a BerTLV created with NewBerTLV, offsets are relative to the given value.
+type Span struct {
+	Offset            int // Offset of the first tag byte.
+	TagLength         int // Number of tag bytes.
+	LengthFieldLength int // Number of bytes of the length field.
+	ValueOffset       int // Offset of the first value byte.
+	ValueLength       int // Number of value bytes as indicated by the length field.
+}
+
+// End returns the offset of the first byte following the BerTLV.
+func (s Span) End() int {
+	return s.ValueOffset + s.ValueLength
+}
+
+// Span returns the location of the BerTLV in the input it was parsed from.
+// The second return value is false if the BerTLV was not parsed.
+// The Span of a pseudo-object for malformed input (see BerTLV.Unparsed) covers the malformed bytes as value.
+func (ber BerTLV) Span() (Span, bool) {
+	if ber.unparsed != nil {
+		return Span{Offset: ber.offset, ValueOffset: ber.offset, ValueLength: len(ber.Value)}, true
+	}
+
+	if ber.header == nil {
+		return Span{}, false
+	}
+
+	tag, err := parseTag(ber.header)
+	if err != nil {
+		return Span{}, false
+	}
+
+	length, lLen, err := parseLength(ber.header[len(tag):])
+	if err != nil {
+		return Span{}, false
+	}
+
+	return Span{
+		Offset:            ber.offset,
+		TagLength:         len(tag),
+		LengthFieldLength: lLen,
+		ValueOffset:       ber.offset + len(ber.header),
+		ValueLength:       length,
+	}, true
+}
+
+// Unparsed returns the reason why the bytes in Value could not be parsed if the BerTLV is a pseudo-object that
+// ParseLenient created for malformed input. Such objects have no Tag. Returns nil for all other BerTLV.
+func (ber BerTLV) Unparsed() error {
+	return ber.unparsed
+}
+
+// Encode returns the byte representation of the BerTLV using the given EncodingMode.
+func (ber BerTLV) Encode(mode EncodingMode) []byte {
+	switch mode {
+	case PreserveEncoding:
+		if raw := ber.RawBytes(); raw != nil {
+			return raw
+		}
+	case CEREncoding:
+		b, _ := ber.EncodeCER()
+		return b
+	}
+
+	return ber.Bytes()
+}
+
 // BytesLength returns the length of the byte representation of the BerTLV.
 // If the value of a BerTLV exceeds a length of 65535 it gets truncated.
+// Use EncodedLen for the exact length of the result of Bytes for BerTLV objects with empty or long tags.
 func (ber BerTLV) BytesLength() int {
+	if ber.unparsed != nil {
+		return len(ber.Value)
+	}
+
 	lVal := len(ber.Value)
 	if lVal > 65535 {
 		lVal = 65535
@@ -380,7 +826,7 @@ func (ber BerTLV) BytesLength() int {
 //
 // If a tag is passed, first order child TLVs are filtered by the given tag and added to the result in the order they are found.
 //
-// Returns nil if no matching BerTLV are found or the BerTLV is not constructed.
+// Returns nil if no matching BerTLV are found or the BerTLV was not parsed as constructed (see ParseOptions.Form).
 func (ber BerTLV) Children(tag BerTag) []BerTLV {
 	var berTLVS []BerTLV
 
@@ -388,10 +834,6 @@ func (ber BerTLV) Children(tag BerTag) []BerTLV {
 		return ber.children
 	}
 
-	if !ber.Tag.IsConstructed() {
-		return nil
-	}
-
 	for _, tlv := range ber.children {
 		if bytes.Equal(tlv.Tag, tag) {
 			berTLVS = append(berTLVS, tlv)
@@ -405,16 +847,12 @@ func (ber BerTLV) Children(tag BerTag) []BerTLV {
 //
 // If a tag is passed, child TLVs are filtered by the given tag and the first matching child is returned.
 //
-// Returns nil if no matching BerTLV are found or the BerTLV is not constructed.
+// Returns nil if no matching BerTLV are found or the BerTLV was not parsed as constructed (see ParseOptions.Form).
 func (ber BerTLV) FirstChild(tag BerTag) *BerTLV {
 	if len(tag) == 0 && len(ber.children) != 0 {
 		return &ber.children[0]
 	}
 
-	if !ber.Tag.IsConstructed() {
-		return nil
-	}
-
 	for _, tlv := range ber.children {
 		if bytes.Equal(tlv.Tag, tag) {
 			return &tlv
@@ -424,78 +862,270 @@ func (ber BerTLV) FirstChild(tag BerTag) *BerTLV {
 	return nil
 }
 
+// Clone returns a deep copy of the BerTLV. Tag, Value, the recorded header and all child objects are copied,
+// so the clone does not share memory with the BerTLV or the input it was parsed from.
+func (ber BerTLV) Clone() BerTLV {
+	clone := ber
+	clone.Tag = BerTag(cloneBytes(ber.Tag))
+	clone.Value = cloneBytes(ber.Value)
+	clone.header = cloneBytes(ber.header)
+	clone.children = BerTLVs(ber.children).Clone()
+	clone.speculative = BerTLVs(ber.speculative).Clone()
+
+	return clone
+}
+
+// cloneBytes returns a copy of b. It returns nil if b is nil.
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append(make([]byte, 0, len(b)), b...)
+}
+
 // String calls BerTLV.Bytes and returns hex encoded (upper-case) result.
 func (ber BerTLV) String() string {
 	return strings.ToUpper(hex.EncodeToString(ber.Bytes()))
 }
 
 // Builder for BER-TLV objects. Use the 'Add' functions to add data.
-// Nested Builders can be used to create constructed BER-TLV objects.
+// Constructed BER-TLV objects can be created with AddConstructed or Begin and End. Their content is written into
+// the same buffer and the length is filled in afterwards, so no intermediate Builders or copies are needed.
+// Alternatively, the Bytes of a nested Builder can be added with AddBytes.
+//
+// Invalid input like incorrectly encoded tags or values that are too long is not added. Instead, the first error is
+// recorded together with its position in the build sequence and all subsequent calls are ignored.
+// The error is returned by Err and BuildBerTLVs.
+//
+// The zero value is an empty Builder ready to use.
 type Builder struct {
 	bytes []byte
+	open  []int // Positions of the length fields of constructed objects started with Begin.
+	calls int   // Number of calls in the build sequence.
+	err   *BuilderError
+
+	segmentSize int // Maximum number of contents bytes of string segments, see SegmentStrings.
+}
+
+// BuilderError is the first error recorded by a Builder.
+type BuilderError struct {
+	Call   int    // Position of the failed call in the build sequence, starting with 0.
+	Method string // Name of the failed method, e.g. "AddBytes".
+	Err    error  // Reason of the failure.
+}
+
+func (e *BuilderError) Error() string {
+	return fmt.Sprintf("%s: builder call %d (%s): %s", packageTag, e.Call, e.Method, e.Err)
+}
+
+// Unwrap returns the reason of the failure.
+func (e *BuilderError) Unwrap() error {
+	return e.Err
+}
+
+// Err returns the first error that occurred while building or nil. The returned error is of type *BuilderError.
+func (bu *Builder) Err() error {
+	if bu.err == nil {
+		return nil
+	}
+
+	return bu.err
+}
+
+// next counts a call of the build sequence and returns false if an error has already been recorded.
+func (bu *Builder) next() bool {
+	bu.calls++
+
+	return bu.err == nil
+}
+
+// fail records err for the current call of the build sequence.
+func (bu *Builder) fail(method string, err error) *Builder {
+	bu.err = &BuilderError{Call: bu.calls - 1, Method: method, Err: err}
+
+	return bu
+}
+
+// Grow grows the capacity of the Builder to guarantee space for another n bytes.
+// Use it to avoid repeated allocations if the size of the result is known in advance.
+func (bu *Builder) Grow(n int) *Builder {
+	if n > cap(bu.bytes)-len(bu.bytes) {
+		grown := make([]byte, len(bu.bytes), len(bu.bytes)+n)
+		copy(grown, bu.bytes)
+		wipe(bu.bytes)
+		bu.bytes = grown
+	}
+
+	return bu
 }
 
 // AddByte adds the given tag with the given value to the Builder.
 // The length is added automatically.
-func (bu Builder) AddByte(tag BerTag, val byte) *Builder {
+func (bu *Builder) AddByte(tag BerTag, val byte) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	if err := tag.CheckEncoding(); err != nil {
+		return bu.fail("AddByte", err)
+	}
+
 	bu.bytes = append(bu.bytes, tag...)
 	bu.bytes = append(bu.bytes, 1)
 	bu.bytes = append(bu.bytes, val)
 
-	return &bu
+	return bu
 }
 
 // AddBytes adds the given tag with the given value to the Builder.
 // The length is added automatically.
-// If the value exceeds a length of 65535 it gets truncated.
-func (bu Builder) AddBytes(tag BerTag, v []byte) *Builder {
-	// tag
-	bu.bytes = append(bu.bytes, tag...)
+// Values that exceed a length of 65535 are not added and an error is recorded.
+func (bu *Builder) AddBytes(tag BerTag, v []byte) *Builder {
+	if !bu.next() {
+		return bu
+	}
 
-	if len(v) == 0 {
-		bu.bytes = append(bu.bytes, []byte{0x00}...)
+	return bu.addBytes("AddBytes", tag, v)
+}
 
-		return &bu
+func (bu *Builder) addBytes(method string, tag BerTag, v []byte) *Builder {
+	if err := tag.CheckEncoding(); err != nil {
+		return bu.fail(method, err)
 	}
 
-	// truncate if > 65535
 	if len(v) > 65535 {
-		v = v[:65535]
+		return bu.fail(method, errors.Errorf("tag %02X: length of value exceeds 65535: %d", []byte(tag), len(v)))
 	}
 
-	prependLengthBytes(&v)
-
-	// value
+	bu.bytes = append(bu.bytes, tag...)
+	bu.bytes = appendLen(bu.bytes, len(v))
 	bu.bytes = append(bu.bytes, v...)
 
-	return &bu
+	return bu
 }
 
-func prependLengthBytes(b *[]byte) {
-	l := buildLen(len(*b))
+// AddEmpty adds the given tag without a value field to the Builder.
+func (bu *Builder) AddEmpty(tag BerTag) *Builder {
+	if !bu.next() {
+		return bu
+	}
 
-	*b = append(l, *b...)
+	return bu.addBytes("AddEmpty", tag, nil)
 }
 
-// AddEmpty adds the given tag without a value field to the Builder.
-func (bu Builder) AddEmpty(tag BerTag) *Builder {
-	return bu.AddBytes(tag, []byte{})
-}
+// AddRaw adds the given BER-TLV encoded bytes to the Builder.
+// The bytes must consist of complete BER-TLV objects, otherwise they are not added and an error is recorded.
+// The content of constructed objects is not checked.
+func (bu *Builder) AddRaw(b []byte) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	if len(b) > 0 {
+		p := parser{opts: ParseOptions{Form: func(BerTag) Form { return FormPrimitive }}}
+		if _, _, err := p.parse(b, 0, false); err != nil {
+			return bu.fail("AddRaw", err)
+		}
+	}
 
-// AddRaw adds the given bytes without further checks to the Builder.
-func (bu Builder) AddRaw(b []byte) *Builder {
 	bu.bytes = append(bu.bytes, b...)
 
-	return &bu
+	return bu
+}
+
+// AddConstructed adds a constructed object with the given tag to the Builder. Everything that is added to the Builder
+// within the function f becomes the value of the object. The length is added automatically.
+func (bu *Builder) AddConstructed(tag BerTag, f func(bu *Builder)) *Builder {
+	bu.Begin(tag)
+	f(bu)
+
+	return bu.End()
+}
+
+// Begin adds the given tag to the Builder and starts a constructed object. Everything that is added to the Builder
+// until the matching call of End becomes the value of the object. Begin and End can be nested.
+func (bu *Builder) Begin(tag BerTag) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	if err := tag.CheckEncoding(); err != nil {
+		return bu.fail("Begin", err)
+	}
+
+	bu.bytes = append(bu.bytes, tag...)
+	bu.open = append(bu.open, len(bu.bytes))
+	// placeholder for a one byte length, moved if more bytes are needed
+	bu.bytes = append(bu.bytes, 0x00)
+
+	return bu
+}
+
+// End completes the constructed object that was started with the last call of Begin and fills in its length using
+// the minimal number of bytes. An error is recorded if there is no matching call of Begin or if the value of the
+// object exceeds a length of 65535.
+func (bu *Builder) End() *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	if len(bu.open) == 0 {
+		return bu.fail("End", errors.New("no constructed object started with Begin"))
+	}
+
+	lenPos := bu.open[len(bu.open)-1]
+	bu.open = bu.open[:len(bu.open)-1]
+
+	if err := bu.fillLen(lenPos); err != nil {
+		return bu.fail("End", err)
+	}
+
+	return bu
+}
+
+// fillLen fills in the length of the value that follows the one byte placeholder at lenPos up to the end of the
+// buffer using the minimal number of bytes.
+func (bu *Builder) fillLen(lenPos int) error {
+	valueLen := len(bu.bytes) - lenPos - 1
+	if valueLen > 65535 {
+		return errors.Errorf("length of value exceeds 65535: %d", valueLen)
+	}
+
+	lenLen := lenOfLen(valueLen)
+
+	if lenLen > 1 {
+		// make room for additional length bytes and move the value
+		for i := 1; i < lenLen; i++ {
+			bu.bytes = append(bu.bytes, 0x00)
+		}
+
+		copy(bu.bytes[lenPos+lenLen:], bu.bytes[lenPos+1:lenPos+1+valueLen])
+	}
+
+	// write the length field in place
+	appendLen(bu.bytes[lenPos:lenPos], valueLen)
+
+	return nil
 }
 
 // BuildBerTLVs calls Parse on the contents of the Builder and returns the resulting BerTLVs.
-// Any errors that occur while parsing are returned.
-func (bu Builder) BuildBerTLVs() (BerTLVs, error) {
+// The error recorded by the Builder, an error for constructed objects that were not completed with End
+// and any errors that occur while parsing are returned.
+func (bu *Builder) BuildBerTLVs() (BerTLVs, error) {
+	if bu.err != nil {
+		return nil, bu.err
+	}
+
+	if len(bu.open) > 0 {
+		return nil, errors.Errorf("%s: %d constructed objects not completed with End", packageTag, len(bu.open))
+	}
+
 	return Parse(bu.bytes)
 }
 
 // Bytes returns the byte representation of the contents of the Builder.
-func (bu Builder) Bytes() []byte {
+// If an error was recorded, the contents up to the failed call are returned, check Err.
+func (bu *Builder) Bytes() []byte {
 	return bu.bytes
 }
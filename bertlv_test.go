@@ -2,12 +2,18 @@ package bertlv
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
+// cmpParsed compares parsed BerTLV objects by tag, value and children and ignores the recorded source encoding.
+var cmpParsed = cmp.Options{cmp.AllowUnexported(BerTLV{}), cmpopts.IgnoreFields(BerTLV{}, "header", "offset")}
+
 func TestNewBerTLV(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -67,7 +73,7 @@ func TestNewBerTLV(t *testing.T) {
 				return
 			}
 
-			if !cmp.Equal(received, tc.expected, cmp.AllowUnexported(BerTLV{})) {
+			if !cmp.Equal(received, tc.expected, cmpParsed) {
 				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
 			}
 		})
@@ -368,7 +374,7 @@ func TestParse(t *testing.T) {
 				return
 			}
 
-			if !cmp.Equal(received, tc.expected, cmp.AllowUnexported(BerTLV{})) {
+			if !cmp.Equal(received, tc.expected, cmpParsed) {
 				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
 			}
 		})
@@ -401,6 +407,11 @@ func TestBerTag_Class(t *testing.T) {
 			tag:      NewThreeByteTag(0xDF, 0x80, 0x90),
 			expected: Private,
 		},
+		{
+			name:     "empty",
+			tag:      BerTag{},
+			expected: Universal,
+		},
 	}
 
 	for _, tc := range tests {
@@ -424,6 +435,11 @@ func TestBerTag_CheckEncoding(t *testing.T) {
 			input:       []byte{0x01, 0x02, 0x03, 0x04},
 			expectError: true,
 		},
+		{
+			name:        "Error: empty tag",
+			input:       []byte{},
+			expectError: true,
+		},
 		{
 			name:        "Error: one byte tag indicates more byte",
 			input:       NewOneByteTag(0x1F),
@@ -1253,7 +1269,7 @@ func TestBuilder_AddByte(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			received := Builder{}.AddByte(tc.inputTag, tc.inputByte).Bytes()
+			received := (&Builder{}).AddByte(tc.inputTag, tc.inputByte).Bytes()
 
 			if !cmp.Equal(received, tc.expected) {
 				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
@@ -1266,10 +1282,11 @@ func TestBuilder_AddBytes(t *testing.T) {
 	tooLongData := make([]byte, 65536)
 
 	tests := []struct {
-		name       string
-		inputTag   BerTag
-		inputBytes []byte
-		expected   []byte
+		name        string
+		inputTag    BerTag
+		inputBytes  []byte
+		expected    []byte
+		expectError bool
 	}{
 		{
 			name:       "add bytes",
@@ -1278,16 +1295,29 @@ func TestBuilder_AddBytes(t *testing.T) {
 			expected:   []byte{0x0A, 0x01, 0xFF},
 		},
 		{
-			name:       "add bytes truncate",
-			inputTag:   NewOneByteTag(0x0A),
-			inputBytes: tooLongData,
-			expected:   append([]byte{0x0A, 0x82, 0xFF, 0xFF}, tooLongData[:len(tooLongData)-1]...),
+			name:        "Error: value too long",
+			inputTag:    NewOneByteTag(0x0A),
+			inputBytes:  tooLongData,
+			expected:    nil,
+			expectError: true,
+		},
+		{
+			name:        "Error: invalid tag",
+			inputTag:    NewOneByteTag(0x1F),
+			inputBytes:  []byte{0xFF},
+			expected:    nil,
+			expectError: true,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			received := Builder{}.AddBytes(tc.inputTag, tc.inputBytes).Bytes()
+			bu := (&Builder{}).AddBytes(tc.inputTag, tc.inputBytes)
+			received := bu.Bytes()
+
+			if err := bu.Err(); (err != nil) != tc.expectError {
+				t.Errorf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
 
 			if !cmp.Equal(received, tc.expected) {
 				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
@@ -1311,7 +1341,7 @@ func TestBuilder_AddEmpty(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			received := Builder{}.AddEmpty(tc.inputTag).Bytes()
+			received := (&Builder{}).AddEmpty(tc.inputTag).Bytes()
 
 			if !cmp.Equal(received, tc.expected) {
 				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
@@ -1335,7 +1365,7 @@ func TestBuilder_AddRaw(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			received := Builder{}.AddRaw(tc.inputBytes).Bytes()
+			received := (&Builder{}).AddRaw(tc.inputBytes).Bytes()
 
 			if !cmp.Equal(received, tc.expected) {
 				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
@@ -1353,7 +1383,7 @@ func TestBuilder_BuildBerTLVs(t *testing.T) {
 	}{
 		{
 			name: "build BerTLVs",
-			builder: Builder{}.
+			builder: (&Builder{}).
 				AddEmpty(NewOneByteTag(0x0A)).
 				AddBytes(NewTwoByteTag(0x3F, 0x0A), []byte{0x10, 0x02, 0x01, 0x02}).
 				AddByte(NewThreeByteTag(0x1F, 0x80, 0x0A), 0xFF),
@@ -1397,9 +1427,1000 @@ func TestBuilder_BuildBerTLVs(t *testing.T) {
 				return
 			}
 
-			if !cmp.Equal(received, tc.expected, cmp.AllowUnexported(BerTLV{})) {
+			if !cmp.Equal(received, tc.expected, cmpParsed) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_RawBytes(t *testing.T) {
+	nonMinimal := []byte{0x5A, 0x81, 0x08, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	nonMinimalConstructed := []byte{0x70, 0x82, 0x00, 0x0E, 0x5A, 0x81, 0x08, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x9F, 0x02, 0x00}
+
+	parse := func(b []byte) BerTLV {
+		tlvs, err := Parse(b)
+		if err != nil {
+			t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+		}
+
+		return tlvs[0]
+	}
+
+	modifiedTag := parse(nonMinimal)
+	modifiedTag.Tag = NewOneByteTag(0x5B)
+
+	modifiedLength := parse(nonMinimal)
+	modifiedLength.Value = modifiedLength.Value[:4]
+
+	modifiedValue := parse(nonMinimal)
+	modifiedValue.Value = []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01}
+
+	tests := []struct {
+		name     string
+		berTLV   BerTLV
+		expected []byte
+	}{
+		{
+			name:     "primitive, non-minimal length",
+			berTLV:   parse(nonMinimal),
+			expected: nonMinimal,
+		},
+		{
+			name:     "constructed, non-minimal lengths",
+			berTLV:   parse(nonMinimalConstructed),
+			expected: nonMinimalConstructed,
+		},
+		{
+			name:     "child, non-minimal length",
+			berTLV:   *parse(nonMinimalConstructed).FirstChild(NewOneByteTag(0x5A)),
+			expected: nonMinimal,
+		},
+		{
+			name:     "value modified, same length",
+			berTLV:   modifiedValue,
+			expected: []byte{0x5A, 0x81, 0x08, 0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01},
+		},
+		{
+			name:     "tag modified",
+			berTLV:   modifiedTag,
+			expected: nil,
+		},
+		{
+			name:     "length modified",
+			berTLV:   modifiedLength,
+			expected: nil,
+		},
+		{
+			name: "not parsed",
+			berTLV: BerTLV{
+				Tag:   NewOneByteTag(0x5A),
+				Value: []byte{0x01},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received := tc.berTLV.RawBytes()
+
+			if !cmp.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLVs_Encode(t *testing.T) {
+	input := []byte{0x70, 0x82, 0x00, 0x0E, 0x5A, 0x81, 0x08, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x9F, 0x02, 0x00, 0x90, 0x01, 0xFF}
+
+	parsed, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	modified := append(BerTLVs{}, parsed...)
+	modified[1].Tag = NewOneByteTag(0x91)
+
+	tests := []struct {
+		name     string
+		berTLVs  BerTLVs
+		mode     EncodingMode
+		expected []byte
+	}{
+		{
+			name:     "preserve",
+			berTLVs:  parsed,
+			mode:     PreserveEncoding,
+			expected: input,
+		},
+		{
+			name:     "preserve, modified object is encoded minimal",
+			berTLVs:  modified,
+			mode:     PreserveEncoding,
+			expected: append(append([]byte{}, input[:18]...), 0x91, 0x01, 0xFF),
+		},
+		{
+			name:     "minimal",
+			berTLVs:  parsed,
+			mode:     MinimalEncoding,
+			expected: []byte{0x70, 0x0E, 0x5A, 0x81, 0x08, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x9F, 0x02, 0x00, 0x90, 0x01, 0xFF},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received := tc.berTLVs.Encode(tc.mode)
+
+			if !cmp.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_Span(t *testing.T) {
+	input := []byte{0x90, 0x01, 0xFF, 0x70, 0x81, 0x0B, 0x5F, 0x20, 0x02, 0x41, 0x42, 0xBF, 0x80, 0x01, 0x02, 0x01, 0x00}
+
+	parsed, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	tests := []struct {
+		name       string
+		berTLV     BerTLV
+		expected   Span
+		expectedOk bool
+	}{
+		{
+			name:       "first object",
+			berTLV:     parsed[0],
+			expected:   Span{Offset: 0, TagLength: 1, LengthFieldLength: 1, ValueOffset: 2, ValueLength: 1},
+			expectedOk: true,
+		},
+		{
+			name:       "constructed object",
+			berTLV:     parsed[1],
+			expected:   Span{Offset: 3, TagLength: 1, LengthFieldLength: 2, ValueOffset: 6, ValueLength: 11},
+			expectedOk: true,
+		},
+		{
+			name:       "child object",
+			berTLV:     *parsed[1].FirstChild(NewTwoByteTag(0x5F, 0x20)),
+			expected:   Span{Offset: 6, TagLength: 2, LengthFieldLength: 1, ValueOffset: 9, ValueLength: 2},
+			expectedOk: true,
+		},
+		{
+			name:       "nested child object",
+			berTLV:     *parsed[1].FirstChild(NewThreeByteTag(0xBF, 0x80, 0x01)).FirstChild(nil),
+			expected:   Span{Offset: 15, TagLength: 1, LengthFieldLength: 1, ValueOffset: 17, ValueLength: 0},
+			expectedOk: true,
+		},
+		{
+			name:       "unparsed",
+			berTLV:     BerTLV{Value: []byte{0x91, 0x05}, offset: 5, unparsed: errors.New("malformed")},
+			expected:   Span{Offset: 5, ValueOffset: 5, ValueLength: 2},
+			expectedOk: true,
+		},
+		{
+			name: "not parsed",
+			berTLV: BerTLV{
+				Tag:   NewOneByteTag(0x90),
+				Value: []byte{0xFF},
+			},
+			expected:   Span{},
+			expectedOk: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, ok := tc.berTLV.Span()
+
+			if ok != tc.expectedOk {
+				t.Errorf("Expected: ok %v, got: %v", tc.expectedOk, ok)
+			}
+
+			if !cmp.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%+v', got: '%+v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestParseFirst(t *testing.T) {
+	tests := []struct {
+		name              string
+		inputBytes        []byte
+		expected          BerTLV
+		expectedLenParsed int
+		expectedRest      []byte
+		expectError       bool
+	}{
+		{
+			name:       "TLV followed by status word",
+			inputBytes: []byte{0x70, 0x03, 0x90, 0x01, 0xFF, 0x90, 0x00},
+			expected: BerTLV{
+				Tag:   NewOneByteTag(0x70),
+				Value: []byte{0x90, 0x01, 0xFF},
+				children: []BerTLV{
+					{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+				},
+			},
+			expectedLenParsed: 5,
+			expectedRest:      []byte{0x90, 0x00},
+		},
+		{
+			name:              "TLV followed by invalid trailer",
+			inputBytes:        []byte{0x5A, 0x01, 0x11, 0x1F},
+			expected:          BerTLV{Tag: NewOneByteTag(0x5A), Value: []byte{0x11}},
+			expectedLenParsed: 3,
+			expectedRest:      []byte{0x1F},
+		},
+		{
+			name:              "no trailer",
+			inputBytes:        []byte{0x5A, 0x01, 0x11},
+			expected:          BerTLV{Tag: NewOneByteTag(0x5A), Value: []byte{0x11}},
+			expectedLenParsed: 3,
+			expectedRest:      []byte{},
+		},
+		{
+			name:        "Error: invalid first TLV",
+			inputBytes:  []byte{0x70, 0x03, 0x90, 0x02, 0xFF},
+			expectError: true,
+		},
+		{
+			name:        "Error: empty",
+			inputBytes:  nil,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, lenParsed, rest, err := ParseFirst(tc.inputBytes)
+			if err != nil && !tc.expectError {
+				t.Errorf("Expected: no error, got: error(%v)", err.Error())
+
+				return
+			}
+
+			if err == nil && tc.expectError {
+				t.Errorf("Expected: error, got: no error")
+
+				return
+			}
+
+			if !cmp.Equal(received, tc.expected, cmpParsed) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+
+			if lenParsed != tc.expectedLenParsed {
+				t.Errorf("Expected: %d bytes parsed, got: %d", tc.expectedLenParsed, lenParsed)
+			}
+
+			if !cmp.Equal(rest, tc.expectedRest) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expectedRest, rest)
+			}
+		})
+	}
+}
+
+func TestNewTag(t *testing.T) {
+	tests := []struct {
+		name        string
+		class       Class
+		constructed bool
+		number      uint64
+		expected    BerTag
+	}{
+		{
+			name:        "universal, constructed, one byte",
+			class:       Universal,
+			constructed: true,
+			number:      16,
+			expected:    BerTag{0x30},
+		},
+		{
+			name:        "application, primitive, one byte",
+			class:       Application,
+			constructed: false,
+			number:      26,
+			expected:    BerTag{0x5A},
+		},
+		{
+			name:        "context specific, primitive, largest one byte number",
+			class:       ContextSpecific,
+			constructed: false,
+			number:      30,
+			expected:    BerTag{0x9E},
+		},
+		{
+			name:        "application, primitive, smallest two byte number",
+			class:       Application,
+			constructed: false,
+			number:      31,
+			expected:    BerTag{0x5F, 0x1F},
+		},
+		{
+			name:        "context specific, primitive",
+			class:       ContextSpecific,
+			constructed: false,
+			number:      2,
+			expected:    BerTag{0x82},
+		},
+		{
+			name:        "application, constructed, two byte",
+			class:       Application,
+			constructed: true,
+			number:      73,
+			expected:    BerTag{0x7F, 0x49},
+		},
+		{
+			name:        "private, primitive, three byte",
+			class:       Private,
+			constructed: false,
+			number:      0x1000,
+			expected:    BerTag{0xDF, 0xA0, 0x00},
+		},
+		{
+			name:        "context specific, constructed, largest three byte number",
+			class:       ContextSpecific,
+			constructed: true,
+			number:      16383,
+			expected:    BerTag{0xBF, 0xFF, 0x7F},
+		},
+		{
+			name:        "universal, primitive, four byte",
+			class:       Universal,
+			constructed: false,
+			number:      16384,
+			expected:    BerTag{0x1F, 0x81, 0x80, 0x00},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received := NewTag(tc.class, tc.constructed, tc.number)
+
+			if !cmp.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", []byte(tc.expected), []byte(received))
+			}
+
+			if number := received.Number(); number != tc.number {
+				t.Errorf("Expected: number %d, got: %d", tc.number, number)
+			}
+
+			if class := received.Class(); class != tc.class {
+				t.Errorf("Expected: class %v, got: %v", tc.class, class)
+			}
+
+			if constructed := received.IsConstructed(); constructed != tc.constructed {
+				t.Errorf("Expected: constructed %v, got: %v", tc.constructed, constructed)
+			}
+		})
+	}
+}
+
+func TestBerTag_Number(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      BerTag
+		expected uint64
+	}{
+		{
+			name:     "one byte",
+			tag:      NewOneByteTag(0x9E),
+			expected: 30,
+		},
+		{
+			name:     "two byte",
+			tag:      NewTwoByteTag(0x9F, 0x02),
+			expected: 2,
+		},
+		{
+			name:     "three byte",
+			tag:      NewThreeByteTag(0xDF, 0x81, 0x01),
+			expected: 129,
+		},
+		{
+			name:     "empty",
+			tag:      BerTag{},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if received := tc.tag.Number(); received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTag_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      BerTag
+		expected string
+	}{
+		{
+			name:     "application, constructed",
+			tag:      NewOneByteTag(0x6F),
+			expected: "[APPLICATION 15] constructed",
+		},
+		{
+			name:     "universal, primitive",
+			tag:      NewOneByteTag(0x02),
+			expected: "[UNIVERSAL 2] primitive",
+		},
+		{
+			name:     "context specific",
+			tag:      NewTwoByteTag(0x9F, 0x02),
+			expected: "[2] primitive",
+		},
+		{
+			name:     "private, constructed",
+			tag:      NewThreeByteTag(0xFF, 0x81, 0x00),
+			expected: "[PRIVATE 128] constructed",
+		},
+		{
+			name:     "empty",
+			tag:      BerTag{},
+			expected: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if received := tc.tag.String(); received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTag_Format(t *testing.T) {
+	tag := NewTwoByteTag(0x5F, 0x20)
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{format: "%X", expected: "5F20"},
+		{format: "%02X", expected: "5F20"},
+		{format: "%x", expected: "5f20"},
+		{format: "% X", expected: "5F 20"},
+		{format: "%v", expected: "[95 32]"},
+		{format: "%#v", expected: "bertlv.BerTag{0x5f, 0x20}"},
+		{format: "%s", expected: "[APPLICATION 32] primitive"},
+		{format: "%30s", expected: "    [APPLICATION 32] primitive"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			if received := fmt.Sprintf(tc.format, tag); received != tc.expected {
+				t.Errorf("Expected: '%s', got: '%s'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestNewTag_InvalidClass(t *testing.T) {
+	// only the two least significant bits of the class are encoded
+	received := NewTag(Class(5), false, 1)
+
+	if !bytes.Equal(received, []byte{0x41}) || received.Class() != Application {
+		t.Errorf("Expected: '41', got: '%X'", received)
+	}
+}
+
+func TestClass_String(t *testing.T) {
+	tests := []struct {
+		class    Class
+		expected string
+	}{
+		{class: Universal, expected: "UNIVERSAL"},
+		{class: Application, expected: "APPLICATION"},
+		{class: ContextSpecific, expected: "CONTEXT"},
+		{class: Private, expected: "PRIVATE"},
+		{class: Class(4), expected: "Class(4)"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expected, func(t *testing.T) {
+			if received := tc.class.String(); received != tc.expected {
 				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
 			}
 		})
 	}
 }
+
+func TestBuilder_AddConstructed(t *testing.T) {
+	twoByteLenData := make([]byte, 200)
+	threeByteLenData := make([]byte, 300)
+
+	tests := []struct {
+		name     string
+		build    func(bu *Builder)
+		expected []byte
+	}{
+		{
+			name: "nested constructed objects",
+			build: func(bu *Builder) {
+				bu.AddConstructed(NewOneByteTag(0x71), func(bu *Builder) {
+					bu.AddConstructed(NewOneByteTag(0xB0), func(bu *Builder) {
+						bu.AddBytes(NewOneByteTag(0x0F), []byte{0x01, 0x02, 0x03, 0x04, 0x05}).
+							AddBytes(NewOneByteTag(0x0E), []byte{0x05, 0x04, 0x03, 0x02, 0x01})
+					})
+				}).AddByte(NewOneByteTag(0x90), 0xFF)
+			},
+			expected: []byte{0x71, 0x10, 0xB0, 0x0E, 0x0F, 0x05, 0x01, 0x02, 0x03, 0x04, 0x05, 0x0E, 0x05, 0x05, 0x04, 0x03, 0x02, 0x01, 0x90, 0x01, 0xFF},
+		},
+		{
+			name: "empty constructed object",
+			build: func(bu *Builder) {
+				bu.AddConstructed(NewOneByteTag(0x70), func(bu *Builder) {})
+			},
+			expected: []byte{0x70, 0x00},
+		},
+		{
+			name: "two byte length",
+			build: func(bu *Builder) {
+				bu.AddConstructed(NewTwoByteTag(0x7F, 0x21), func(bu *Builder) {
+					bu.AddBytes(NewOneByteTag(0x04), twoByteLenData[:197])
+				})
+			},
+			expected: append([]byte{0x7F, 0x21, 0x81, 0xC8, 0x04, 0x81, 0xC5}, twoByteLenData[:197]...),
+		},
+		{
+			name: "three byte length in two byte length",
+			build: func(bu *Builder) {
+				bu.Begin(NewOneByteTag(0x70)).
+					Begin(NewOneByteTag(0x71)).
+					AddBytes(NewOneByteTag(0x04), threeByteLenData[:253]).
+					End().
+					AddBytes(NewOneByteTag(0x05), twoByteLenData[:3]).
+					End()
+			},
+			expected: append(append(append([]byte{0x70, 0x82, 0x01, 0x09, 0x71, 0x82, 0x01, 0x00, 0x04, 0x81, 0xFD}, threeByteLenData[:253]...),
+				0x05, 0x03), twoByteLenData[:3]...),
+		},
+		{
+			name: "end without begin",
+			build: func(bu *Builder) {
+				bu.AddEmpty(NewOneByteTag(0x90)).End()
+			},
+			expected: []byte{0x90, 0x00},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bu := &Builder{}
+			tc.build(bu)
+
+			if received := bu.Bytes(); !bytes.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func BenchmarkBuilder_NestedBuilders(b *testing.B) {
+	value := make([]byte, 64)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		inner := Builder{}
+		inner.AddBytes(NewOneByteTag(0x0F), value).AddBytes(NewOneByteTag(0x0E), value)
+
+		middle := Builder{}
+		middle.AddBytes(NewOneByteTag(0xB0), inner.Bytes()).AddByte(NewOneByteTag(0x90), 0xFF)
+
+		outer := Builder{}
+		outer.AddBytes(NewOneByteTag(0x71), middle.Bytes())
+	}
+}
+
+func BenchmarkBuilder_AddConstructed(b *testing.B) {
+	value := make([]byte, 64)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		outer := Builder{}
+		outer.AddConstructed(NewOneByteTag(0x71), func(bu *Builder) {
+			bu.AddConstructed(NewOneByteTag(0xB0), func(bu *Builder) {
+				bu.AddBytes(NewOneByteTag(0x0F), value).AddBytes(NewOneByteTag(0x0E), value)
+			}).AddByte(NewOneByteTag(0x90), 0xFF)
+		})
+	}
+}
+
+func BenchmarkBuilder_BeginEnd(b *testing.B) {
+	value := make([]byte, 64)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		outer := Builder{}
+		outer.Begin(NewOneByteTag(0x71)).
+			Begin(NewOneByteTag(0xB0)).
+			AddBytes(NewOneByteTag(0x0F), value).
+			AddBytes(NewOneByteTag(0x0E), value).
+			End().
+			AddByte(NewOneByteTag(0x90), 0xFF).
+			End()
+	}
+}
+
+func BenchmarkBuilder_BeginEndGrow(b *testing.B) {
+	value := make([]byte, 64)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		outer := Builder{}
+		outer.Grow(160).
+			Begin(NewOneByteTag(0x71)).
+			Begin(NewOneByteTag(0xB0)).
+			AddBytes(NewOneByteTag(0x0F), value).
+			AddBytes(NewOneByteTag(0x0E), value).
+			End().
+			AddByte(NewOneByteTag(0x90), 0xFF).
+			End()
+	}
+}
+
+func TestBuilder_Grow(t *testing.T) {
+	bu := &Builder{}
+	bu.AddByte(NewOneByteTag(0x90), 0xFF).Grow(100)
+
+	if c := cap(bu.Bytes()); c < 103 {
+		t.Errorf("Expected: capacity >= 103, got: %d", c)
+	}
+
+	expected := []byte{0x90, 0x01, 0xFF}
+	if received := bu.Bytes(); !bytes.Equal(received, expected) {
+		t.Errorf("Expected: '%X', got: '%X'", expected, received)
+	}
+}
+
+func TestBuilder_Err(t *testing.T) {
+	tests := []struct {
+		name          string
+		build         func(bu *Builder)
+		expected      []byte
+		expectedError *BuilderError
+	}{
+		{
+			name: "no error",
+			build: func(bu *Builder) {
+				bu.AddEmpty(NewOneByteTag(0x90)).AddRaw([]byte{0x91, 0x00})
+			},
+			expected: []byte{0x90, 0x00, 0x91, 0x00},
+		},
+		{
+			name: "invalid tag, subsequent calls are ignored",
+			build: func(bu *Builder) {
+				bu.AddEmpty(NewOneByteTag(0x90)).
+					AddByte(NewTwoByteTag(0x5F, 0x80), 0x01).
+					AddEmpty(NewOneByteTag(0x91))
+			},
+			expected:      []byte{0x90, 0x00},
+			expectedError: &BuilderError{Call: 1, Method: "AddByte"},
+		},
+		{
+			name: "empty tag",
+			build: func(bu *Builder) {
+				bu.AddEmpty(nil)
+			},
+			expectedError: &BuilderError{Call: 0, Method: "AddEmpty"},
+		},
+		{
+			name: "invalid tag of constructed object",
+			build: func(bu *Builder) {
+				bu.AddConstructed(NewOneByteTag(0x3F), func(bu *Builder) {
+					bu.AddEmpty(NewOneByteTag(0x90))
+				})
+			},
+			expectedError: &BuilderError{Call: 0, Method: "Begin"},
+		},
+		{
+			name: "malformed raw bytes",
+			build: func(bu *Builder) {
+				bu.AddEmpty(NewOneByteTag(0x90)).AddRaw([]byte{0x91, 0x02, 0x01})
+			},
+			expected:      []byte{0x90, 0x00},
+			expectedError: &BuilderError{Call: 1, Method: "AddRaw"},
+		},
+		{
+			name: "end without begin",
+			build: func(bu *Builder) {
+				bu.AddEmpty(NewOneByteTag(0x90)).End()
+			},
+			expected:      []byte{0x90, 0x00},
+			expectedError: &BuilderError{Call: 1, Method: "End"},
+		},
+		{
+			name: "constructed value too long",
+			build: func(bu *Builder) {
+				bu.AddConstructed(NewOneByteTag(0x70), func(bu *Builder) {
+					bu.AddBytes(NewOneByteTag(0x04), make([]byte, 65535))
+				})
+			},
+			expectedError: &BuilderError{Call: 2, Method: "End"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bu := &Builder{}
+			tc.build(bu)
+
+			err := bu.Err()
+
+			if tc.expectedError == nil {
+				if err != nil {
+					t.Errorf("Expected: no error, got: error(%v)", err.Error())
+				}
+
+				if received := bu.Bytes(); !bytes.Equal(received, tc.expected) {
+					t.Errorf("Expected: '%X', got: '%X'", tc.expected, received)
+				}
+
+				return
+			}
+
+			var builderErr *BuilderError
+			if !errors.As(err, &builderErr) {
+				t.Fatalf("Expected: BuilderError, got: %v", err)
+			}
+
+			if builderErr.Call != tc.expectedError.Call || builderErr.Method != tc.expectedError.Method || builderErr.Err == nil {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expectedError, builderErr)
+			}
+
+			if tc.expected != nil && !bytes.Equal(bu.Bytes(), tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, bu.Bytes())
+			}
+
+			if _, err = bu.BuildBerTLVs(); err == nil {
+				t.Errorf("Expected: error, got: no error")
+			}
+		})
+	}
+}
+
+func TestBuilder_BuildBerTLVs_NotCompleted(t *testing.T) {
+	_, err := (&Builder{}).Begin(NewOneByteTag(0x70)).AddEmpty(NewOneByteTag(0x90)).BuildBerTLVs()
+	if err == nil {
+		t.Errorf("Expected: error, got: no error")
+	}
+}
+
+func TestBerTLVs_Clone(t *testing.T) {
+	input := []byte{0xE1, 0x09, 0x9F, 0x1A, 0x02, 0x01, 0x02, 0xA0, 0x02, 0x81, 0x00, 0x5A, 0x01, 0x12}
+
+	tlvs, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	expected, err := Parse(append([]byte{}, input...))
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	received := tlvs.Clone()
+
+	// the clone does not change when the input buffer is reused
+	for i := range input {
+		input[i] = 0xFF
+	}
+
+	if !cmp.Equal(received, expected, cmp.AllowUnexported(BerTLV{})) {
+		t.Errorf("Expected: '%v', got: '%v'", expected, received)
+	}
+
+	if !bytes.Equal(received.Encode(PreserveEncoding), expected.Encode(PreserveEncoding)) {
+		t.Errorf("Expected: '%X', got: '%X'", expected.Encode(PreserveEncoding), received.Encode(PreserveEncoding))
+	}
+
+	// the original is not changed by modifying the clone
+	clone := expected[0].Clone()
+	clone.children[0].Value[0] = 0xAA
+	clone.Tag[0] = 0xE2
+
+	if expected[0].children[0].Value[0] != 0x01 || expected[0].Tag[0] != 0xE1 {
+		t.Errorf("Expected: unchanged original, got: '%v'", expected[0])
+	}
+
+	if BerTLVs(nil).Clone() != nil {
+		t.Errorf("Expected: nil")
+	}
+
+	if empty := (BerTLV{}).Clone(); empty.Tag != nil || empty.Value != nil || empty.children != nil {
+		t.Errorf("Expected: empty clone, got: '%v'", empty)
+	}
+}
+
+// failingWriter accepts n bytes and fails afterwards.
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if len(b) > w.n {
+		written := w.n
+		w.n = 0
+
+		return written, errors.New("write failed")
+	}
+
+	w.n -= len(b)
+
+	return len(b), nil
+}
+
+func TestBerTLV_AppendBytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		berTLV    BerTLV
+		expected  []byte
+		truncated bool
+	}{
+		{
+			name:     "primitive",
+			berTLV:   BerTLV{Tag: NewTwoByteTag(0x5F, 0x20), Value: []byte{0x41, 0x42}},
+			expected: []byte{0x5F, 0x20, 0x02, 0x41, 0x42},
+		},
+		{
+			name:     "empty tag",
+			berTLV:   BerTLV{Value: []byte{0x01}},
+			expected: []byte{0x00, 0x01, 0x01},
+		},
+		{
+			name:      "tag truncated",
+			berTLV:    BerTLV{Tag: BerTag{0x9F, 0x81, 0x82, 0x03}},
+			expected:  []byte{0x9F, 0x81, 0x82, 0x00},
+			truncated: true,
+		},
+		{
+			name:     "unparsed",
+			berTLV:   BerTLV{Value: []byte{0x5A, 0x08}, unparsed: errors.New("malformed")},
+			expected: []byte{0x5A, 0x08},
+		},
+		{
+			name:     "two byte length",
+			berTLV:   BerTLV{Tag: NewOneByteTag(0x04), Value: make([]byte, 200)},
+			expected: append([]byte{0x04, 0x81, 0xC8}, make([]byte, 200)...),
+		},
+		{
+			name:      "value truncated",
+			berTLV:    BerTLV{Tag: NewOneByteTag(0x04), Value: make([]byte, 70000)},
+			expected:  append([]byte{0x04, 0x82, 0xFF, 0xFF}, make([]byte, 65535)...),
+			truncated: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			prefix := []byte{0x90, 0x00}
+
+			if received := tc.berTLV.AppendBytes(prefix); !bytes.Equal(received, append(prefix, tc.expected...)) {
+				t.Errorf("Expected: '%X', got: '%X'", append(prefix, tc.expected...), received)
+			}
+
+			if received := tc.berTLV.Bytes(); !bytes.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, received)
+			}
+
+			if received := tc.berTLV.EncodedLen(); received != len(tc.expected) {
+				t.Errorf("Expected: %d, got: %d", len(tc.expected), received)
+			}
+
+			buf := &bytes.Buffer{}
+
+			n, err := tc.berTLV.WriteTo(buf)
+			checked, checkedErr := tc.berTLV.AppendBytesChecked(prefix)
+
+			// the checked variants do not truncate
+			if tc.truncated {
+				if err == nil || n != 0 || buf.Len() != 0 {
+					t.Errorf("Expected: error without writing, got: '%X' (%d bytes, error(%v))", buf.Bytes(), n, err)
+				}
+
+				if checkedErr == nil || !bytes.Equal(checked, prefix) {
+					t.Errorf("Expected: error and '%X', got: '%X', error(%v)", prefix, checked, checkedErr)
+				}
+
+				return
+			}
+
+			if err != nil || n != int64(len(tc.expected)) || !bytes.Equal(buf.Bytes(), tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X' (%d bytes, error(%v))", tc.expected, buf.Bytes(), n, err)
+			}
+
+			if checkedErr != nil || !bytes.Equal(checked, append(prefix, tc.expected...)) {
+				t.Errorf("Expected: '%X', got: '%X', error(%v)", append(prefix, tc.expected...), checked, checkedErr)
+			}
+		})
+	}
+}
+
+func TestBerTLVs_AppendBytes(t *testing.T) {
+	tlvs, err := Parse([]byte{0x70, 0x81, 0x06, 0x5A, 0x01, 0x12, 0x90, 0x01, 0xFF, 0x9F, 0x02, 0x00})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	expected := []byte{0x70, 0x06, 0x5A, 0x01, 0x12, 0x90, 0x01, 0xFF, 0x9F, 0x02, 0x00}
+
+	if received := tlvs.EncodedLen(); received != len(expected) {
+		t.Errorf("Expected: %d, got: %d", len(expected), received)
+	}
+
+	dst := make([]byte, 0, 64)
+
+	// encoding into a buffer with sufficient capacity does not allocate
+	allocs := testing.AllocsPerRun(10, func() {
+		dst = tlvs.AppendBytes(dst[:0])
+	})
+
+	if allocs != 0 {
+		t.Errorf("Expected: no allocations, got: %v", allocs)
+	}
+
+	if !bytes.Equal(dst, expected) || !bytes.Equal(tlvs.Bytes(), expected) {
+		t.Errorf("Expected: '%X', got: '%X' and '%X'", expected, dst, tlvs.Bytes())
+	}
+
+	buf := &bytes.Buffer{}
+
+	n, err := tlvs.WriteTo(buf)
+	if err != nil || n != int64(len(expected)) || !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Expected: '%X', got: '%X' (%d bytes, error(%v))", expected, buf.Bytes(), n, err)
+	}
+
+	for _, limit := range []int{0, 1, 2, 9} {
+		n, err = tlvs.WriteTo(&failingWriter{n: limit})
+		if err == nil || n != int64(limit) {
+			t.Errorf("Expected: error after %d bytes, got: %d bytes, error(%v)", limit, n, err)
+		}
+	}
+
+	// values are not copied, only the buffer for the tag and length bytes is allocated
+	w := &failingWriter{n: math.MaxInt32}
+
+	allocs = testing.AllocsPerRun(10, func() {
+		_, _ = tlvs.WriteTo(w)
+	})
+
+	if allocs > 1 {
+		t.Errorf("Expected: at most one allocation, got: %v", allocs)
+	}
+
+	truncated := append(tlvs.Clone(), BerTLV{Tag: NewOneByteTag(0x04), Value: make([]byte, 70000)})
+
+	if n, err = truncated.WriteTo(buf); err == nil || n != 0 {
+		t.Errorf("Expected: error without writing, got: %d bytes, error(%v)", n, err)
+	}
+
+	if checked, err := truncated.AppendBytesChecked(dst[:0]); err == nil || len(checked) != 0 {
+		t.Errorf("Expected: error and no bytes, got: '%X', error(%v)", checked, err)
+	}
+
+	if checked, err := tlvs.AppendBytesChecked(dst[:0]); err != nil || !bytes.Equal(checked, expected) {
+		t.Errorf("Expected: '%X', got: '%X', error(%v)", expected, checked, err)
+	}
+
+	if BerTLVs(nil).Bytes() != nil || BerTLVs(nil).EncodedLen() != 0 {
+		t.Errorf("Expected: nil and 0")
+	}
+}
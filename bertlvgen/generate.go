@@ -0,0 +1,417 @@
+// Package bertlvgen generates random but valid BER-TLV encodings for property based testing of code that consumes
+// objects parsed by package bertlv, and mutates valid encodings into malformed ones for fuzzing.
+//
+// Encoding and TLVs implement testing/quick.Generator with DefaultConfig, so they can be used directly as arguments
+// of functions passed to quick.Check. For other configurations create a Generator with New and use
+// Generator.Values for quick.Config.Values. Malformed generates encodings that Parse rejects, see Mutate.
+package bertlvgen
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing/quick"
+
+	"github.com/pkg/errors"
+	"github.com/skythen/bertlv"
+)
+
+// TagForm is the number of bytes of a generated tag.
+type TagForm int
+
+const (
+	TagOneByte   TagForm = iota // Tag numbers 0 - 30 in one byte.
+	TagTwoByte   TagForm = iota // Tag numbers 31 - 127 in two bytes.
+	TagThreeByte TagForm = iota // Tag numbers 128 - 16383 in three bytes.
+)
+
+// LengthForm is the encoding of a generated length field.
+type LengthForm int
+
+const (
+	LengthShort    LengthForm = iota // One byte for lengths 0 - 127.
+	LengthOneByte  LengthForm = iota // '81' followed by one byte for lengths up to 255.
+	LengthTwoBytes LengthForm = iota // '82' followed by two bytes for lengths up to 65535.
+)
+
+// max returns the greatest length that can be encoded with the LengthForm.
+func (f LengthForm) max() int {
+	switch f {
+	case LengthShort:
+		return 127
+	case LengthOneByte:
+		return 255
+	default:
+		return 65535
+	}
+}
+
+// min returns the smallest length for which the LengthForm is the minimal encoding.
+func (f LengthForm) min() int {
+	switch f {
+	case LengthShort:
+		return 0
+	case LengthOneByte:
+		return 128
+	default:
+		return 256
+	}
+}
+
+// Config configures the BER-TLV structures created by a Generator.
+type Config struct {
+	// MaxDepth is the maximum nesting depth of objects. Top level objects have depth 1, so with a MaxDepth of 1
+	// only primitive objects are created. Must be at least 1.
+	MaxDepth int
+	// MaxBreadth is the maximum number of top level objects and of child objects of each constructed object.
+	// At least one top level object is created, constructed objects may be empty. Must be at least 1.
+	MaxBreadth int
+	// Classes are the classes of the created tags, nil for all classes. Tag '00' (end-of-contents) is never created.
+	Classes []bertlv.Class
+	// TagForms are the forms of the created tags, nil for all forms.
+	TagForms []TagForm
+	// LengthForms are the forms of the created length fields, nil for all forms.
+	LengthForms []LengthForm
+	// NonMinimalLengths allows length fields that use more bytes than necessary, e.g. '81 05'. Parse accepts such
+	// lengths, but BerTLV.Bytes encodes them minimally. If false, only value lengths whose minimal length field
+	// has one of LengthForms are created.
+	NonMinimalLengths bool
+	// MinValueLength and MaxValueLength limit the length of the values of primitive objects. The values of
+	// constructed objects are limited to 65535 bytes; child objects that do not fit are left out.
+	MinValueLength int
+	MaxValueLength int
+}
+
+// DefaultConfig is used by the quick.Generator implementations of this package. Values are long enough to use all
+// length forms. It is read once, when the first value is generated, so changes after that have no effect.
+var DefaultConfig = Config{
+	MaxDepth:       4,
+	MaxBreadth:     4,
+	MaxValueLength: 300,
+}
+
+// Generator creates random BER-TLV encodings according to a Config.
+type Generator struct {
+	cfg         Config
+	classes     []bertlv.Class
+	tagForms    []TagForm
+	lengthForms []LengthForm
+}
+
+// New returns a Generator for the given Config. An error is returned if the Config is invalid or if no primitive
+// value length satisfies both the value length limits and the length forms.
+func New(cfg Config) (*Generator, error) {
+	if cfg.MaxDepth < 1 {
+		return nil, errors.Errorf("bertlvgen: MaxDepth must be at least 1, got %d", cfg.MaxDepth)
+	}
+
+	if cfg.MaxBreadth < 1 {
+		return nil, errors.Errorf("bertlvgen: MaxBreadth must be at least 1, got %d", cfg.MaxBreadth)
+	}
+
+	if cfg.MinValueLength < 0 || cfg.MaxValueLength < cfg.MinValueLength || cfg.MaxValueLength > 65535 {
+		return nil, errors.Errorf("bertlvgen: invalid value length range %d - %d", cfg.MinValueLength, cfg.MaxValueLength)
+	}
+
+	g := &Generator{
+		cfg:         cfg,
+		classes:     cfg.Classes,
+		tagForms:    cfg.TagForms,
+		lengthForms: cfg.LengthForms,
+	}
+
+	if g.classes == nil {
+		g.classes = []bertlv.Class{bertlv.Universal, bertlv.Application, bertlv.ContextSpecific, bertlv.Private}
+	}
+
+	if g.tagForms == nil {
+		g.tagForms = []TagForm{TagOneByte, TagTwoByte, TagThreeByte}
+	}
+
+	if g.lengthForms == nil {
+		g.lengthForms = []LengthForm{LengthShort, LengthOneByte, LengthTwoBytes}
+	}
+
+	if len(g.classes) == 0 || len(g.tagForms) == 0 || len(g.lengthForms) == 0 {
+		return nil, errors.New("bertlvgen: Classes, TagForms and LengthForms must not be empty")
+	}
+
+	for _, c := range g.classes {
+		if c < bertlv.Universal || c > bertlv.Private {
+			return nil, errors.Errorf("bertlvgen: invalid class %d", c)
+		}
+	}
+
+	for _, f := range g.tagForms {
+		if f < TagOneByte || f > TagThreeByte {
+			return nil, errors.Errorf("bertlvgen: invalid tag form %d", f)
+		}
+	}
+
+	for _, f := range g.lengthForms {
+		if f < LengthShort || f > LengthTwoBytes {
+			return nil, errors.Errorf("bertlvgen: invalid length form %d", f)
+		}
+	}
+
+	if len(g.valueLengthForms()) == 0 {
+		return nil, errors.Errorf("bertlvgen: no value length in range %d - %d can be encoded with the length forms", cfg.MinValueLength, cfg.MaxValueLength)
+	}
+
+	return g, nil
+}
+
+// Bytes returns the encoding of between one and MaxBreadth random top level objects.
+func (g *Generator) Bytes(r *rand.Rand) []byte {
+	var b []byte
+
+	for i, n := 0, 1+r.Intn(g.cfg.MaxBreadth); i < n; i++ {
+		b = append(b, g.object(r, 1)...)
+	}
+
+	return b
+}
+
+// BerTLVs returns random objects parsed from the result of Bytes, so they have a Span and RawBytes like objects
+// parsed from real input. Generated encodings are valid, so an error indicates a bug in the Generator or in Parse.
+func (g *Generator) BerTLVs(r *rand.Rand) (bertlv.BerTLVs, error) {
+	tlvs, err := bertlv.Parse(g.Bytes(r))
+	if err != nil {
+		return nil, errors.Wrap(err, "bertlvgen: invalid encoding generated")
+	}
+
+	return tlvs, nil
+}
+
+// mustBerTLVs returns the result of BerTLVs for the implementations of quick.Generator and quick.Config.Values,
+// which can not return errors.
+func (g *Generator) mustBerTLVs(r *rand.Rand) bertlv.BerTLVs {
+	tlvs, err := g.BerTLVs(r)
+	if err != nil {
+		panic(err)
+	}
+
+	return tlvs
+}
+
+// Values returns a function for quick.Config.Values that generates the arguments of the function f, which must be
+// the function passed to quick.Check. Arguments of type []byte or Encoding are generated with Bytes, arguments of
+// type bertlv.BerTLVs or TLVs with BerTLVs, arguments of type bertlv.BerTLV as the first object of BerTLVs and
+// arguments of type Malformed by mutating the result of Bytes. All other arguments are generated with quick.Value.
+// The returned function panics if an argument can not be generated, since quick.Config.Values can not return errors.
+func (g *Generator) Values(f interface{}) func(args []reflect.Value, r *rand.Rand) {
+	ft := reflect.TypeOf(f)
+
+	return func(args []reflect.Value, r *rand.Rand) {
+		for i := range args {
+			args[i] = g.value(ft.In(i), r)
+		}
+	}
+}
+
+// value returns a random value of type t.
+func (g *Generator) value(t reflect.Type, r *rand.Rand) reflect.Value {
+	var v interface{}
+
+	switch t {
+	case reflect.TypeOf([]byte(nil)), reflect.TypeOf(Encoding(nil)):
+		v = g.Bytes(r)
+	case reflect.TypeOf(bertlv.BerTLVs(nil)), reflect.TypeOf(TLVs(nil)):
+		v = g.mustBerTLVs(r)
+	case reflect.TypeOf(bertlv.BerTLV{}):
+		v = g.mustBerTLVs(r)[0]
+	case reflect.TypeOf(Malformed(nil)):
+		v, _ = Mutate(r, g.Bytes(r))
+	default:
+		value, ok := quick.Value(t, r)
+		if !ok {
+			panic("bertlvgen: cannot create arbitrary value of type " + t.String())
+		}
+
+		return value
+	}
+
+	return reflect.ValueOf(v).Convert(t)
+}
+
+// object returns the encoding of a random object at the given depth.
+func (g *Generator) object(r *rand.Rand, depth int) []byte {
+	if depth < g.cfg.MaxDepth && r.Intn(2) == 0 {
+		if b, ok := g.constructed(r, depth); ok {
+			return b
+		}
+	}
+
+	length, form := g.valueLength(r)
+
+	value := make([]byte, length)
+	r.Read(value)
+
+	return append(g.header(r, false, form, length), value...)
+}
+
+// constructed returns the encoding of a random constructed object at the given depth. It returns false if the
+// length of the value can not be encoded with the length forms.
+func (g *Generator) constructed(r *rand.Rand, depth int) ([]byte, bool) {
+	var (
+		value []byte
+		fit   = -1 // length of the longest value that can be encoded
+	)
+
+	if _, ok := g.lengthForm(r, 0); ok {
+		fit = 0
+	}
+
+	for i, n := 0, r.Intn(g.cfg.MaxBreadth+1); i < n; i++ {
+		child := g.object(r, depth+1)
+		if len(value)+len(child) > 65535 {
+			break
+		}
+
+		value = append(value, child...)
+
+		if _, ok := g.lengthForm(r, len(value)); ok {
+			fit = len(value)
+		}
+	}
+
+	if fit < 0 {
+		return nil, false
+	}
+
+	value = value[:fit]
+	form, _ := g.lengthForm(r, fit)
+
+	return append(g.header(r, true, form, fit), value...), true
+}
+
+// header returns a random tag followed by the length field for length with the given form.
+func (g *Generator) header(r *rand.Rand, constructed bool, form LengthForm, length int) []byte {
+	b := []byte(g.tag(r, constructed))
+
+	switch form {
+	case LengthShort:
+		return append(b, byte(length))
+	case LengthOneByte:
+		return append(b, 0x81, byte(length))
+	default:
+		return append(b, 0x82, byte(length>>8), byte(length))
+	}
+}
+
+// tag returns a random tag of one of the configured classes and forms.
+func (g *Generator) tag(r *rand.Rand, constructed bool) bertlv.BerTag {
+	class := g.classes[r.Intn(len(g.classes))]
+
+	var number uint64
+
+	switch g.tagForms[r.Intn(len(g.tagForms))] {
+	case TagOneByte:
+		number = uint64(r.Intn(31))
+		// '00' is used for end-of-contents and as padding
+		if number == 0 && class == bertlv.Universal && !constructed {
+			number = 1
+		}
+	case TagTwoByte:
+		number = uint64(31 + r.Intn(97))
+	default:
+		number = uint64(128 + r.Intn(16256))
+	}
+
+	return bertlv.NewTag(class, constructed, number)
+}
+
+// valueLengthForms returns the length forms that can be used for primitive values and the range of value lengths
+// for each of them.
+func (g *Generator) valueLengthForms() []lengthRange {
+	var ranges []lengthRange
+
+	for _, f := range g.lengthForms {
+		lo, hi := f.min(), f.max()
+		if g.cfg.NonMinimalLengths {
+			lo = 0
+		}
+
+		if lo < g.cfg.MinValueLength {
+			lo = g.cfg.MinValueLength
+		}
+
+		if hi > g.cfg.MaxValueLength {
+			hi = g.cfg.MaxValueLength
+		}
+
+		if lo <= hi {
+			ranges = append(ranges, lengthRange{form: f, lo: lo, hi: hi})
+		}
+	}
+
+	return ranges
+}
+
+// lengthRange is a range of value lengths that can be encoded with a length form.
+type lengthRange struct {
+	form   LengthForm
+	lo, hi int
+}
+
+// valueLength returns a random length for a primitive value and the form of its length field.
+func (g *Generator) valueLength(r *rand.Rand) (int, LengthForm) {
+	ranges := g.valueLengthForms()
+	lr := ranges[r.Intn(len(ranges))]
+
+	return lr.lo + r.Intn(lr.hi-lr.lo+1), lr.form
+}
+
+// lengthForm returns a random configured length form that can encode length. It returns false if there is none.
+func (g *Generator) lengthForm(r *rand.Rand, length int) (LengthForm, bool) {
+	var forms []LengthForm
+
+	for _, f := range g.lengthForms {
+		if length <= f.max() && (g.cfg.NonMinimalLengths || length >= f.min()) {
+			forms = append(forms, f)
+		}
+	}
+
+	if len(forms) == 0 {
+		return 0, false
+	}
+
+	return forms[r.Intn(len(forms))], true
+}
+
+// Encoding is a valid BER-TLV encoding of one or more objects. It implements quick.Generator.
+type Encoding []byte
+
+// Generate implements quick.Generator and returns the result of Generator.Bytes for DefaultConfig.
+// size is ignored, the structure is limited by DefaultConfig only. Generate panics if DefaultConfig is invalid.
+func (Encoding) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Encoding(defaultGenerator().Bytes(r)))
+}
+
+// TLVs are parsed BER-TLV objects. It implements quick.Generator.
+type TLVs bertlv.BerTLVs
+
+// Generate implements quick.Generator and returns the result of Generator.BerTLVs for DefaultConfig.
+// size is ignored, the structure is limited by DefaultConfig only. Generate panics if DefaultConfig is invalid.
+func (TLVs) Generate(r *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(TLVs(defaultGenerator().mustBerTLVs(r)))
+}
+
+var (
+	defaultOnce sync.Once
+	defaultGen  *Generator
+	defaultErr  error
+)
+
+// defaultGenerator returns the Generator for DefaultConfig, which is created on first use.
+func defaultGenerator() *Generator {
+	defaultOnce.Do(func() {
+		defaultGen, defaultErr = New(DefaultConfig)
+	})
+
+	if defaultErr != nil {
+		panic(defaultErr)
+	}
+
+	return defaultGen
+}
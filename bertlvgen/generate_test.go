@@ -0,0 +1,299 @@
+package bertlvgen
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/skythen/bertlv"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		expectError bool
+	}{
+		{name: "default", cfg: DefaultConfig, expectError: false},
+		{name: "primitive only", cfg: Config{MaxDepth: 1, MaxBreadth: 1}, expectError: false},
+		{name: "no depth", cfg: Config{MaxBreadth: 1}, expectError: true},
+		{name: "no breadth", cfg: Config{MaxDepth: 1}, expectError: true},
+		{name: "negative min value length", cfg: Config{MaxDepth: 1, MaxBreadth: 1, MinValueLength: -1}, expectError: true},
+		{name: "min greater max", cfg: Config{MaxDepth: 1, MaxBreadth: 1, MinValueLength: 5, MaxValueLength: 4}, expectError: true},
+		{name: "value too long", cfg: Config{MaxDepth: 1, MaxBreadth: 1, MaxValueLength: 65536}, expectError: true},
+		{name: "empty classes", cfg: Config{MaxDepth: 1, MaxBreadth: 1, Classes: []bertlv.Class{}}, expectError: true},
+		{name: "invalid class", cfg: Config{MaxDepth: 1, MaxBreadth: 1, Classes: []bertlv.Class{4}}, expectError: true},
+		{name: "invalid tag form", cfg: Config{MaxDepth: 1, MaxBreadth: 1, TagForms: []TagForm{3}}, expectError: true},
+		{name: "invalid length form", cfg: Config{MaxDepth: 1, MaxBreadth: 1, LengthForms: []LengthForm{-1}}, expectError: true},
+		{
+			name:        "length form does not fit value lengths",
+			cfg:         Config{MaxDepth: 1, MaxBreadth: 1, MaxValueLength: 100, LengthForms: []LengthForm{LengthOneByte}},
+			expectError: true,
+		},
+		{
+			name:        "non-minimal length form fits value lengths",
+			cfg:         Config{MaxDepth: 1, MaxBreadth: 1, MaxValueLength: 100, LengthForms: []LengthForm{LengthOneByte}, NonMinimalLengths: true},
+			expectError: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := New(tc.cfg)
+
+			if err != nil && !tc.expectError {
+				t.Errorf("Expected: no error, got: error(%v)", err.Error())
+
+				return
+			}
+
+			if err == nil && tc.expectError {
+				t.Errorf("Expected: error, got: no error")
+
+				return
+			}
+
+			if !tc.expectError && g == nil {
+				t.Errorf("Expected: Generator, got: nil")
+			}
+		})
+	}
+}
+
+// stats records properties of generated objects.
+type stats struct {
+	depth       int
+	breadth     int
+	classes     map[bertlv.Class]bool
+	tagLengths  map[int]bool
+	lenLengths  map[int]bool
+	nonMinimal  bool
+	constructed bool
+	minValue    int
+	maxValue    int
+}
+
+func (s *stats) add(tlvs []bertlv.BerTLV, depth int) {
+	if depth > s.depth {
+		s.depth = depth
+	}
+
+	if len(tlvs) > s.breadth {
+		s.breadth = len(tlvs)
+	}
+
+	for _, tlv := range tlvs {
+		span, _ := tlv.Span()
+
+		s.classes[tlv.Tag.Class()] = true
+		s.tagLengths[span.TagLength] = true
+		s.lenLengths[span.LengthFieldLength] = true
+
+		if !bytes.Equal(tlv.RawBytes(), tlv.Bytes()) {
+			s.nonMinimal = true
+		}
+
+		if tlv.Tag.IsConstructed() {
+			s.constructed = true
+			s.add(tlv.Children(nil), depth+1)
+
+			continue
+		}
+
+		if len(tlv.Value) < s.minValue {
+			s.minValue = len(tlv.Value)
+		}
+
+		if len(tlv.Value) > s.maxValue {
+			s.maxValue = len(tlv.Value)
+		}
+	}
+}
+
+func TestGenerator_Bytes(t *testing.T) {
+	tests := []struct {
+		name               string
+		cfg                Config
+		expectedClasses    int
+		expectedTagLengths int
+		expectedLenLengths int
+	}{
+		{
+			name:               "default",
+			cfg:                DefaultConfig,
+			expectedClasses:    4,
+			expectedTagLengths: 3,
+			expectedLenLengths: 3,
+		},
+		{
+			name:               "flat",
+			cfg:                Config{MaxDepth: 1, MaxBreadth: 8, MinValueLength: 2, MaxValueLength: 10},
+			expectedClasses:    4,
+			expectedTagLengths: 3,
+			expectedLenLengths: 1,
+		},
+		{
+			name: "restricted",
+			cfg: Config{
+				MaxDepth:       3,
+				MaxBreadth:     2,
+				Classes:        []bertlv.Class{bertlv.ContextSpecific},
+				TagForms:       []TagForm{TagTwoByte},
+				LengthForms:    []LengthForm{LengthShort},
+				MaxValueLength: 200,
+			},
+			expectedClasses:    1,
+			expectedTagLengths: 1,
+			expectedLenLengths: 1,
+		},
+		{
+			name: "non-minimal",
+			cfg: Config{
+				MaxDepth:          3,
+				MaxBreadth:        3,
+				LengthForms:       []LengthForm{LengthOneByte, LengthTwoBytes},
+				NonMinimalLengths: true,
+				MaxValueLength:    16,
+			},
+			expectedClasses:    4,
+			expectedTagLengths: 3,
+			expectedLenLengths: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := New(tc.cfg)
+			if err != nil {
+				t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+			}
+
+			r := rand.New(rand.NewSource(1))
+			s := &stats{
+				classes:    map[bertlv.Class]bool{},
+				tagLengths: map[int]bool{},
+				lenLengths: map[int]bool{},
+				minValue:   65536,
+			}
+
+			for i := 0; i < 500; i++ {
+				b := g.Bytes(r)
+
+				tlvs, err := bertlv.Parse(b)
+				if err != nil {
+					t.Fatalf("Expected: valid encoding, got: '%X' error(%v)", b, err.Error())
+				}
+
+				for _, tlv := range tlvs {
+					if tlv.Tag[0] == 0x00 {
+						t.Errorf("Expected: no tag '00', got: '%X'", b)
+					}
+				}
+
+				s.add(tlvs, 1)
+			}
+
+			if s.depth != tc.cfg.MaxDepth {
+				t.Errorf("Expected: depth %d, got: %d", tc.cfg.MaxDepth, s.depth)
+			}
+
+			if s.breadth != tc.cfg.MaxBreadth {
+				t.Errorf("Expected: breadth %d, got: %d", tc.cfg.MaxBreadth, s.breadth)
+			}
+
+			if s.constructed != (tc.cfg.MaxDepth > 1) {
+				t.Errorf("Expected: constructed objects %v, got: %v", tc.cfg.MaxDepth > 1, s.constructed)
+			}
+
+			if len(s.classes) != tc.expectedClasses || len(s.tagLengths) != tc.expectedTagLengths || len(s.lenLengths) != tc.expectedLenLengths {
+				t.Errorf("Expected: %d classes, %d tag lengths and %d length field lengths, got: %v, %v and %v", tc.expectedClasses, tc.expectedTagLengths, tc.expectedLenLengths, s.classes, s.tagLengths, s.lenLengths)
+			}
+
+			if s.nonMinimal != tc.cfg.NonMinimalLengths {
+				t.Errorf("Expected: non-minimal lengths %v, got: %v", tc.cfg.NonMinimalLengths, s.nonMinimal)
+			}
+
+			if s.minValue < tc.cfg.MinValueLength || s.maxValue > tc.cfg.MaxValueLength {
+				t.Errorf("Expected: value lengths %d - %d, got: %d - %d", tc.cfg.MinValueLength, tc.cfg.MaxValueLength, s.minValue, s.maxValue)
+			}
+		})
+	}
+}
+
+func TestEncoding_Generate(t *testing.T) {
+	// minimally encoded objects are re-encoded unchanged
+	f := func(e Encoding) bool {
+		tlvs, err := bertlv.Parse(e)
+
+		return err == nil && bytes.Equal(tlvs.Bytes(), e)
+	}
+
+	if err := quick.Check(f, &quick.Config{Rand: rand.New(rand.NewSource(1))}); err != nil {
+		t.Errorf("Expected: no error, got: error(%v)", err.Error())
+	}
+}
+
+func TestTLVs_Generate(t *testing.T) {
+	f := func(tlvs TLVs) bool {
+		if len(tlvs) == 0 {
+			return false
+		}
+
+		for _, tlv := range tlvs {
+			if tlv.RawBytes() == nil {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if err := quick.Check(f, &quick.Config{Rand: rand.New(rand.NewSource(1))}); err != nil {
+		t.Errorf("Expected: no error, got: error(%v)", err.Error())
+	}
+}
+
+func TestGenerator_BerTLVs(t *testing.T) {
+	g, err := New(DefaultConfig)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		tlvs, err := g.BerTLVs(r)
+		if err != nil || len(tlvs) == 0 {
+			t.Fatalf("Expected: objects, got: %d objects, error(%v)", len(tlvs), err)
+		}
+	}
+
+	// the Generator for DefaultConfig is created once
+	if defaultGenerator() != defaultGenerator() {
+		t.Errorf("Expected: same Generator")
+	}
+}
+
+func TestGenerator_Values(t *testing.T) {
+	g, err := New(Config{MaxDepth: 2, MaxBreadth: 2, MaxValueLength: 8, TagForms: []TagForm{TagOneByte}})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	f := func(b []byte, e Encoding, tlvs bertlv.BerTLVs, tlv bertlv.BerTLV, m Malformed, n int) bool {
+		for _, enc := range [][]byte{b, e, tlvs.Bytes(), tlv.Bytes()} {
+			if _, err := bertlv.Parse(enc); err != nil || len(enc) > 2*(2+2*(2+8)) {
+				return false
+			}
+		}
+
+		_, err := bertlv.Parse(m)
+
+		return err != nil
+	}
+
+	if err := quick.Check(f, &quick.Config{Rand: rand.New(rand.NewSource(1)), Values: g.Values(f)}); err != nil {
+		t.Errorf("Expected: no error, got: error(%v)", err.Error())
+	}
+}
@@ -0,0 +1,210 @@
+package bertlvgen
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/skythen/bertlv"
+)
+
+// Mutation is a modification of a single object of a valid encoding that results in a malformed encoding.
+type Mutation int
+
+const (
+	MutateLengthOverflow  Mutation = iota // The length indicates a value that exceeds the enclosing object or input.
+	MutateLengthUnderflow Mutation = iota // The length indicates a value that is shorter than the actual value.
+	MutateLengthForm      Mutation = iota // The length field has an unsupported form like '80' (indefinite) or '83'.
+	MutateTagContinuation Mutation = iota // The last tag byte indicates that another tag byte follows.
+	MutateConstructed     Mutation = iota // A primitive tag is changed to constructed, so the value is parsed as objects.
+	MutateTruncate        Mutation = iota // The encoding ends within the header or value of the object.
+	MutateInsert          Mutation = iota // A random byte is inserted into the value without adjusting lengths.
+	MutateDelete          Mutation = iota // A byte of the value is removed without adjusting lengths.
+)
+
+// mutations are all Mutation values.
+var mutations = []Mutation{
+	MutateLengthOverflow,
+	MutateLengthUnderflow,
+	MutateLengthForm,
+	MutateTagContinuation,
+	MutateConstructed,
+	MutateTruncate,
+	MutateInsert,
+	MutateDelete,
+}
+
+// String returns the name of the Mutation.
+func (m Mutation) String() string {
+	switch m {
+	case MutateLengthOverflow:
+		return "length overflow"
+	case MutateLengthUnderflow:
+		return "length underflow"
+	case MutateLengthForm:
+		return "length form"
+	case MutateTagContinuation:
+		return "tag continuation"
+	case MutateConstructed:
+		return "constructed"
+	case MutateTruncate:
+		return "truncate"
+	case MutateInsert:
+		return "insert"
+	case MutateDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// maxMutationAttempts is the number of random mutations Mutate tries before it falls back to appending an
+// incomplete object.
+const maxMutationAttempts = 32
+
+// Mutate returns a copy of the valid BER-TLV encoding b in which a random object is modified by a random Mutation
+// so that bertlv.Parse returns an error, and the applied Mutation. The result is near-valid: all other objects and
+// most of the modified one are unchanged, so code behind the first parsing steps is reached.
+//
+// Mutations that happen to result in another valid encoding are discarded. If no mutation succeeds, e.g. because b
+// is empty, an object that ends after its tag is appended and MutateTruncate is returned. b is not modified.
+func Mutate(r *rand.Rand, b []byte) ([]byte, Mutation) {
+	var objects []object
+
+	if tlvs, err := bertlv.Parse(b); err == nil {
+		objects = collect(nil, tlvs, len(b))
+	}
+
+	if len(objects) > 0 {
+		for i := 0; i < maxMutationAttempts; i++ {
+			o := objects[r.Intn(len(objects))]
+			m := mutations[r.Intn(len(mutations))]
+
+			mutated, ok := mutate(r, b, o, m)
+			if !ok {
+				continue
+			}
+
+			if _, err := bertlv.Parse(mutated); err != nil {
+				return mutated, m
+			}
+		}
+	}
+
+	// a universal primitive tag without length
+	return append(append(make([]byte, 0, len(b)+1), b...), 0x04), MutateTruncate
+}
+
+// object is the location of a parsed object and the end of its enclosing object or input.
+type object struct {
+	span      bertlv.Span
+	parentEnd int
+}
+
+// collect appends the locations of tlvs and all their children to objects.
+func collect(objects []object, tlvs []bertlv.BerTLV, parentEnd int) []object {
+	for _, tlv := range tlvs {
+		span, ok := tlv.Span()
+		if !ok {
+			continue
+		}
+
+		objects = append(objects, object{span: span, parentEnd: parentEnd})
+		objects = collect(objects, tlv.Children(nil), span.End())
+	}
+
+	return objects
+}
+
+// mutate applies m to the object o of b. It returns false if m is not applicable to o.
+func mutate(r *rand.Rand, b []byte, o object, m Mutation) ([]byte, bool) {
+	s := o.span
+	lengthOffset := s.Offset + s.TagLength
+
+	switch m {
+	case MutateLengthOverflow:
+		return setLength(b, s, o.parentEnd-s.ValueOffset+1+r.Intn(16))
+	case MutateLengthUnderflow:
+		if s.ValueLength == 0 {
+			return nil, false
+		}
+
+		return setLength(b, s, r.Intn(s.ValueLength))
+	case MutateLengthForm:
+		forms := []byte{0x80, 0x83, 0x84, 0xFF}
+
+		return replace(b, lengthOffset, 1, []byte{forms[r.Intn(len(forms))]}), true
+	case MutateTagContinuation:
+		last := lengthOffset - 1
+
+		switch s.TagLength {
+		case 1:
+			return replace(b, last, 1, []byte{b[last] | 0x1F}), true
+		case 2:
+			return replace(b, last, 1, []byte{b[last] | 0x80}), true
+		default:
+			// Parse does not check the last byte of three byte tags
+			return nil, false
+		}
+	case MutateConstructed:
+		if b[s.Offset]&0x20 != 0 || s.ValueLength == 0 {
+			return nil, false
+		}
+
+		return replace(b, s.Offset, 1, []byte{b[s.Offset] | 0x20}), true
+	case MutateTruncate:
+		end := s.Offset + 1 + r.Intn(s.End()-s.Offset-1)
+
+		return append([]byte(nil), b[:end]...), true
+	case MutateInsert:
+		at := s.ValueOffset + r.Intn(s.ValueLength+1)
+
+		return replace(b, at, 0, []byte{byte(r.Intn(256))}), true
+	case MutateDelete:
+		if s.ValueLength == 0 {
+			return nil, false
+		}
+
+		return replace(b, s.ValueOffset+r.Intn(s.ValueLength), 1, nil), true
+	default:
+		return nil, false
+	}
+}
+
+// setLength returns a copy of b with the length field of the object at s replaced by one for length. The form of
+// the length field is kept if possible. It returns false if length can not be encoded.
+func setLength(b []byte, s bertlv.Span, length int) ([]byte, bool) {
+	var field []byte
+
+	switch {
+	case s.LengthFieldLength == 1 && length <= 127:
+		field = []byte{byte(length)}
+	case s.LengthFieldLength == 2 && length <= 255:
+		field = []byte{0x81, byte(length)}
+	case length <= 65535:
+		field = []byte{0x82, byte(length >> 8), byte(length)}
+	default:
+		return nil, false
+	}
+
+	return replace(b, s.Offset+s.TagLength, s.LengthFieldLength, field), true
+}
+
+// replace returns a copy of b in which the n bytes at offset are replaced by r.
+func replace(b []byte, offset int, n int, r []byte) []byte {
+	result := make([]byte, 0, len(b)-n+len(r))
+	result = append(result, b[:offset]...)
+	result = append(result, r...)
+
+	return append(result, b[offset+n:]...)
+}
+
+// Malformed is a near-valid but malformed BER-TLV encoding created by Mutate. It implements quick.Generator.
+type Malformed []byte
+
+// Generate implements quick.Generator and returns a mutated result of Generator.Bytes for DefaultConfig.
+// size is ignored. Generate panics if DefaultConfig is invalid.
+func (Malformed) Generate(r *rand.Rand, size int) reflect.Value {
+	b, _ := Mutate(r, defaultGenerator().Bytes(r))
+
+	return reflect.ValueOf(Malformed(b))
+}
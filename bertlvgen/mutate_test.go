@@ -0,0 +1,157 @@
+package bertlvgen
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/skythen/bertlv"
+)
+
+func TestMutate(t *testing.T) {
+	g, err := New(DefaultConfig)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	r := rand.New(rand.NewSource(1))
+	applied := map[Mutation]int{}
+
+	for i := 0; i < 1000; i++ {
+		b := g.Bytes(r)
+		original := append([]byte(nil), b...)
+
+		mutated, m := Mutate(r, b)
+		applied[m]++
+
+		if !bytes.Equal(b, original) {
+			t.Fatalf("Expected: input unchanged, got: '%X'", b)
+		}
+
+		if _, err := bertlv.Parse(mutated); err == nil {
+			t.Fatalf("Expected: error for %s of '%X', got: no error for '%X'", m, b, mutated)
+		}
+
+		// ParseLenient finds the problem as well
+		if _, problems := bertlv.ParseLenient(mutated, bertlv.ParseOptions{}); len(problems) == 0 {
+			t.Fatalf("Expected: problems for %s of '%X', got: none for '%X'", m, b, mutated)
+		}
+	}
+
+	for _, m := range mutations {
+		if applied[m] == 0 {
+			t.Errorf("Expected: %s applied, got: %v", m, applied)
+		}
+	}
+}
+
+func TestMutate_Fallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		inputs   []byte
+		expected []byte
+	}{
+		{name: "empty", inputs: nil, expected: []byte{0x04}},
+		{name: "invalid input", inputs: []byte{0x04, 0x05}, expected: []byte{0x04, 0x05, 0x04}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, m := Mutate(rand.New(rand.NewSource(1)), tc.inputs)
+
+			if m != MutateTruncate || !bytes.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X' (%s), got: '%X' (%s)", tc.expected, MutateTruncate, received, m)
+			}
+		})
+	}
+}
+
+func TestMutate_Mutations(t *testing.T) {
+	// 70 06 5F20 03 414243 followed by 81 01 00
+	b := []byte{0x70, 0x06, 0x5F, 0x20, 0x03, 0x41, 0x42, 0x43, 0x81, 0x01, 0x00}
+	tlvs, _ := bertlv.Parse(b)
+	objects := collect(nil, tlvs, len(b))
+
+	if len(objects) != 3 {
+		t.Fatalf("Expected: 3 objects, got: %d", len(objects))
+	}
+
+	tests := []struct {
+		name     string
+		object   int
+		mutation Mutation
+		expected []byte
+	}{
+		{
+			name:     "tag continuation one byte",
+			object:   2,
+			mutation: MutateTagContinuation,
+			expected: []byte{0x70, 0x06, 0x5F, 0x20, 0x03, 0x41, 0x42, 0x43, 0x9F, 0x01, 0x00},
+		},
+		{
+			name:     "tag continuation two byte",
+			object:   1,
+			mutation: MutateTagContinuation,
+			expected: []byte{0x70, 0x06, 0x5F, 0xA0, 0x03, 0x41, 0x42, 0x43, 0x81, 0x01, 0x00},
+		},
+		{
+			name:     "constructed",
+			object:   1,
+			mutation: MutateConstructed,
+			expected: []byte{0x70, 0x06, 0x7F, 0x20, 0x03, 0x41, 0x42, 0x43, 0x81, 0x01, 0x00},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, ok := mutate(rand.New(rand.NewSource(1)), b, objects[tc.object], tc.mutation)
+
+			if !ok || !bytes.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X' (%v)", tc.expected, received, ok)
+			}
+		})
+	}
+
+	// length overflow exceeds the enclosing object
+	received, _ := mutate(rand.New(rand.NewSource(1)), b, objects[1], MutateLengthOverflow)
+	if received[4] <= 0x04 {
+		t.Errorf("Expected: length greater than 4, got: '%X'", received)
+	}
+
+	// not applicable mutations
+	if _, ok := mutate(rand.New(rand.NewSource(1)), b, objects[0], MutateConstructed); ok {
+		t.Errorf("Expected: constructed object not mutated to constructed")
+	}
+
+	three := []byte{0x9F, 0x81, 0x01, 0x00}
+	tlvs, _ = bertlv.Parse(three)
+
+	if _, ok := mutate(rand.New(rand.NewSource(1)), three, collect(nil, tlvs, len(three))[0], MutateTagContinuation); ok {
+		t.Errorf("Expected: three byte tag not mutated")
+	}
+}
+
+func TestMalformed_Generate(t *testing.T) {
+	f := func(m Malformed) bool {
+		_, err := bertlv.Parse(m)
+
+		return err != nil
+	}
+
+	if err := quick.Check(f, &quick.Config{Rand: rand.New(rand.NewSource(1))}); err != nil {
+		t.Errorf("Expected: no error, got: error(%v)", err.Error())
+	}
+}
+
+func TestMutation_String(t *testing.T) {
+	for _, m := range append(mutations, Mutation(-1)) {
+		if m.String() == "" {
+			t.Errorf("Expected: name of mutation %d, got: empty string", m)
+		}
+	}
+
+	if received := MutateLengthForm.String(); received != "length form" {
+		t.Errorf("Expected: length form, got: %s", received)
+	}
+}
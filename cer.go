@@ -0,0 +1,794 @@
+package bertlv
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// CERError is returned by ValidateCER for input that is not encoded according to CER.
+type CERError struct {
+	Offset int   // Offset in the input of the first tag byte of the object that violates CER.
+	Err    error // Violated rule.
+}
+
+func (e *CERError) Error() string {
+	return fmt.Sprintf("invalid CER at offset %d: %s", e.Offset, e.Err)
+}
+
+// Unwrap returns the violated rule.
+func (e *CERError) Unwrap() error {
+	return e.Err
+}
+
+// isCERString returns true if values of the universal type with the given tag number are segmented in CER,
+// i.e. BIT STRING, OCTET STRING and the restricted character string types.
+func isCERString(number uint64) bool {
+	switch number {
+	case UniversalBitString, UniversalOctetString, UniversalUTF8String, 18, UniversalPrintableString, 20, 21,
+		UniversalIA5String, 25, 26, 27, 28, UniversalBMPString:
+		return true
+	default:
+		return false
+	}
+}
+
+// EncodeCER returns the encoding of the BerTLV according to the Canonical Encoding Rules of ITU-T X.690 clause 9:
+// constructed objects use the indefinite length form and their children are encoded recursively, strings of
+// universal types are segmented at CERSegmentSize bytes and the components of a SET or SET OF are sorted.
+//
+// Tags and the values of universal types that ValidateCER checks are converted to their canonical form, e.g. a
+// BOOLEAN TRUE is encoded as FF, leading zeros of an INTEGER are removed and a GeneralizedTime is converted to UTC
+// without trailing zeros in the fractional seconds. Returns an error if a value is invalid or can not be converted
+// without changing it, like a REAL in decimal NR1 form or malformed input (see BerTLV.Unparsed).
+// Parse does not support the indefinite length form, use ValidateCER to check the result.
+func (ber BerTLV) EncodeCER() ([]byte, error) {
+	b, err := appendCER(nil, ber)
+	if err != nil {
+		return nil, errors.Wrap(err, packageTag+": can not encode CER")
+	}
+
+	return b, nil
+}
+
+// EncodeCER returns the encodings of BerTLVs according to the Canonical Encoding Rules, see BerTLV.EncodeCER.
+func (t BerTLVs) EncodeCER() ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+
+	for _, tlv := range t {
+		if b, err = appendCER(b, tlv); err != nil {
+			return nil, errors.Wrap(err, packageTag+": can not encode CER")
+		}
+	}
+
+	return b, nil
+}
+
+// appendCER appends the CER encoding of ber to b, see BerTLV.EncodeCER.
+func appendCER(b []byte, ber BerTLV) ([]byte, error) {
+	if ber.unparsed != nil {
+		return nil, errors.Wrap(ber.unparsed, "malformed input")
+	}
+
+	tag, err := cerTag(ber.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag.Class() == Universal && isCERString(tag.Number()) {
+		return appendCERString(b, tag, ber)
+	}
+
+	if !tag.IsConstructed() {
+		value, err := cerValue(BerTLV{Tag: tag, Value: ber.Value})
+		if err != nil {
+			return nil, err
+		}
+
+		return appendPrimitive(b, tag, value)
+	}
+
+	if number := tag.Number(); tag.Class() == Universal && !isConstructedUniversal(number) {
+		return nil, errors.Errorf("tag %02X: universal type %d must be primitive", []byte(tag), number)
+	}
+
+	children := ber.children
+
+	// the value was not parsed as constructed, e.g. because of ParseOptions.Form
+	if children == nil && len(ber.Value) > 0 {
+		p := parser{}
+
+		parsed, _, err := p.parse(ber.Value, 0, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "tag %02X: invalid constructed value", []byte(tag))
+		}
+
+		children = parsed
+	}
+
+	b = append(b, tag...)
+	b = append(b, 0x80)
+
+	if tag.Class() == Universal && tag.Number() == UniversalSet {
+		b, err = appendCERSet(b, children)
+	} else {
+		for _, child := range children {
+			if b, err = appendCER(b, child); err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// end-of-contents
+	return append(b, 0x00, 0x00), nil
+}
+
+// isConstructedUniversal returns true if values of the universal type with the given tag number may be constructed
+// in CER, i.e. SEQUENCE, SET, EXTERNAL, EMBEDDED PDV, CHARACTER STRING and the strings segmented in CER.
+func isConstructedUniversal(number uint64) bool {
+	switch number {
+	case UniversalSequence, UniversalSet, 8, 11, 29:
+		return true
+	default:
+		return isCERString(number)
+	}
+}
+
+// cerTag returns tag encoded with the minimal number of bytes.
+func cerTag(tag BerTag) (BerTag, error) {
+	if len(tag) == 0 {
+		return nil, errors.New("tag is empty")
+	}
+
+	if len(tag) == 1 && tag[0] == 0x00 {
+		return nil, errors.New("tag 00 is reserved for end-of-contents")
+	}
+
+	minimal := NewTag(tag.Class(), tag.IsConstructed(), tag.Number())
+	if bytes.Equal(minimal, tag) {
+		return tag, nil
+	}
+
+	if err := tag.CheckEncoding(); err != nil {
+		return nil, errors.Wrapf(err, "tag %02X", []byte(tag))
+	}
+
+	return minimal, nil
+}
+
+// appendPrimitive appends the encoding of a primitive object with the minimal length field to b.
+func appendPrimitive(b []byte, tag BerTag, value []byte) ([]byte, error) {
+	if len(value) > 65535 {
+		return nil, errors.Errorf("tag %02X: length of value exceeds 65535: %d", []byte(tag), len(value))
+	}
+
+	b = append(b, tag...)
+	b = appendLen(b, len(value))
+
+	return append(b, value...), nil
+}
+
+// appendCERString appends a string of a universal type in primitive form if its contents bytes fit into a single
+// segment, otherwise in constructed form with segments of CERSegmentSize contents bytes.
+func appendCERString(b []byte, tag BerTag, ber BerTLV) ([]byte, error) {
+	number := tag.Number()
+
+	var (
+		contents []byte
+		err      error
+	)
+
+	if number == UniversalBitString {
+		var bs asn1.BitString
+		if bs, err = ber.BitString(); err == nil {
+			contents, err = encodeBitString(bs)
+		}
+	} else {
+		// the segments of character strings are encoded as OCTET STRING
+		if contents, err = ber.OctetString(); err == nil {
+			err = checkCERString(tag, contents)
+		}
+
+		number = UniversalOctetString
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contents) <= CERSegmentSize {
+		return appendPrimitive(b, append(BerTag{tag[0] &^ 0x20}, tag[1:]...), contents)
+	}
+
+	b = append(b, constructedTag(tag)...)
+	b = append(b, 0x80)
+	b = appendSegments(b, number, contents, CERSegmentSize)
+
+	return append(b, 0x00, 0x00), nil
+}
+
+// checkCERString checks the contents bytes of the character string types whose values ValidateCER checks.
+func checkCERString(tag BerTag, contents []byte) error {
+	var err error
+
+	switch tag.Number() {
+	case UniversalUTF8String:
+		err = checkUTF8String(contents)
+	case UniversalPrintableString:
+		err = checkPrintableString(contents)
+	case UniversalIA5String:
+		err = checkIA5String(contents)
+	}
+
+	return errors.Wrapf(err, "tag %02X", []byte(tag))
+}
+
+// cerValue returns the canonical value of a primitive object with a universal tag, see BerTLV.EncodeCER.
+// Values of other classes are returned unchanged.
+func cerValue(ber BerTLV) ([]byte, error) {
+	if ber.Tag.Class() != Universal {
+		return ber.Value, nil
+	}
+
+	v := ber.Value
+
+	switch ber.Tag.Number() {
+	case UniversalBoolean:
+		if len(v) != 1 {
+			return nil, errors.Errorf("tag %02X: BOOLEAN must have a length of 1, got %d", []byte(ber.Tag), len(v))
+		}
+
+		if v[0] != 0x00 {
+			return []byte{0xFF}, nil
+		}
+	case UniversalInteger, UniversalEnumerated:
+		if len(v) == 0 {
+			return nil, errors.Errorf("tag %02X: integer value is empty", []byte(ber.Tag))
+		}
+
+		// remove leading bytes as long as the first nine bits are all ones or all zeros
+		for len(v) > 1 && ((v[0] == 0x00 && v[1]&0x80 == 0) || (v[0] == 0xFF && v[1]&0x80 != 0)) {
+			v = v[1:]
+		}
+	case UniversalReal:
+		return cerReal(ber)
+	case UniversalUTCTime:
+		if checkCERValue(ber) == nil {
+			return v, nil
+		}
+
+		t, err := ber.UTCTime()
+		if err != nil {
+			return nil, err
+		}
+
+		if t = t.UTC(); t.Year() < 1950 || t.Year() > 2049 {
+			return nil, errors.Errorf("tag %02X: year %d can not be encoded as UTCTime", []byte(ber.Tag), t.Year())
+		}
+
+		return []byte(t.Format("060102150405Z")), nil
+	case UniversalGeneralizedTime:
+		if checkCERValue(ber) == nil {
+			return v, nil
+		}
+
+		t, err := ber.GeneralizedTime()
+		if err != nil {
+			return nil, err
+		}
+
+		if t = t.UTC(); t.Year() < 0 || t.Year() > 9999 {
+			return nil, errors.Errorf("tag %02X: year %d can not be encoded as GeneralizedTime", []byte(ber.Tag), t.Year())
+		}
+
+		return []byte(t.Format("20060102150405.999999999Z")), nil
+	default:
+		if err := checkCERValue(ber); err != nil {
+			return nil, err
+		}
+	}
+
+	return v, nil
+}
+
+// cerReal returns the canonical value of a REAL. Binary values in base 8 or 16, with a scaling factor or an even
+// mantissa are converted to base 2 if this is possible without rounding.
+func cerReal(ber BerTLV) ([]byte, error) {
+	err := checkCERReal(ber)
+	if err == nil {
+		return ber.Value, nil
+	}
+
+	if ber.Value[0]&0x80 == 0 {
+		return nil, err
+	}
+
+	f, accuracy, decodeErr := decodeBinaryReal(ber.Value)
+	if decodeErr != nil {
+		return nil, errors.Wrapf(decodeErr, "tag %02X", []byte(ber.Tag))
+	}
+
+	if accuracy != big.Exact {
+		return nil, errors.Errorf("tag %02X: binary REAL can not be converted to base 2 without rounding", []byte(ber.Tag))
+	}
+
+	return encodeReal(f), nil
+}
+
+// appendCERSet appends the CER encodings of the components of a SET or SET OF in ascending order.
+// Since both have the same tag, a SET whose components do not have distinct tags is treated as SET OF.
+func appendCERSet(b []byte, children []BerTLV) ([]byte, error) {
+	type component struct {
+		tag      BerTag
+		encoding []byte
+	}
+
+	var err error
+
+	components := make([]component, len(children))
+	for i, child := range children {
+		if components[i].encoding, err = appendCER(nil, child); err != nil {
+			return nil, err
+		}
+
+		// appendCER checked the tag
+		components[i].tag, _ = cerTag(child.Tag)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		return compareTags(components[i].tag, components[j].tag) < 0
+	})
+
+	setOf := false
+	for i := 1; i < len(components) && !setOf; i++ {
+		setOf = compareTags(components[i-1].tag, components[i].tag) == 0
+	}
+
+	// the components of a SET OF are sorted by their encodings, see X.690 11.6
+	if setOf {
+		sort.SliceStable(components, func(i, j int) bool {
+			return compareEncodings(components[i].encoding, components[j].encoding) < 0
+		})
+	}
+
+	for _, c := range components {
+		b = append(b, c.encoding...)
+	}
+
+	return b, nil
+}
+
+// compareTags compares two tags by their canonical order as components of a SET, i.e. by class and tag number.
+func compareTags(a BerTag, b BerTag) int {
+	if classA, classB := a.Class(), b.Class(); classA != classB {
+		if classA < classB {
+			return -1
+		}
+
+		return 1
+	}
+
+	if numberA, numberB := a.Number(), b.Number(); numberA != numberB {
+		if numberA < numberB {
+			return -1
+		}
+
+		return 1
+	}
+
+	return 0
+}
+
+// compareEncodings compares two encodings of components of a SET OF as octet strings, with the shorter one padded
+// with trailing zeros.
+func compareEncodings(a []byte, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+
+	if c := bytes.Compare(a[:n], b[:n]); c != 0 {
+		return c
+	}
+
+	// the longer encoding is greater unless its remaining bytes are all zero
+	sign, rest := 1, a[n:]
+	if len(b) > len(a) {
+		sign, rest = -1, b[n:]
+	}
+
+	for _, r := range rest {
+		if r != 0x00 {
+			return sign
+		}
+	}
+
+	return 0
+}
+
+// cerFrame is a constructed object whose contents are being validated.
+type cerFrame struct {
+	tag    BerTag
+	offset int
+
+	// components of a SET or SET OF: whether they are sorted like those of a SET or a SET OF so far and the location
+	// of the previous component
+	set              bool
+	sortedByTag      bool
+	sortedByEncoding bool
+	prevTag          BerTag
+	prevStart        int
+	prevEnd          int
+
+	// string in constructed form: expected tag number of the segments and the segments so far
+	segment       uint64
+	segments      int
+	lastSegment   []byte
+	contentLength int
+}
+
+// ValidateCER checks whether b consists of one or more objects encoded according to the Canonical Encoding Rules
+// of ITU-T X.690 clause 9. The following rules are checked:
+//
+//   - tags and definite lengths are encoded with the minimal number of bytes
+//   - constructed objects use the indefinite length form, primitive objects the definite length form
+//   - strings of universal types are primitive if their contents have at most CERSegmentSize bytes, otherwise
+//     they consist of primitive segments with CERSegmentSize contents bytes, except for the last one
+//   - the components of a SET or SET OF are sorted, i.e. they have distinct tags in ascending order like those of a
+//     SET or are sorted by their encodings like those of a SET OF, since both have the same tag
+//   - the values of BOOLEAN, INTEGER, ENUMERATED, NULL, OBJECT IDENTIFIER, RELATIVE-OID, BIT STRING, REAL,
+//     UTCTime, GeneralizedTime, UTF8String, PrintableString and IA5String are valid and canonical
+//
+// Type-specific rules are only checked for universal tags, since the type of implicitly tagged values is unknown.
+// Returns a *CERError for the first violation. Note that Parse does not support the indefinite length form.
+func ValidateCER(b []byte) error {
+	if len(b) == 0 {
+		return errors.Errorf("%s: TLV has length 0", packageTag)
+	}
+
+	var stack []cerFrame
+
+	for pos := 0; pos < len(b) || len(stack) > 0; {
+		if pos >= len(b) {
+			return &CERError{Offset: stack[len(stack)-1].offset, Err: errors.New("end-of-contents is missing")}
+		}
+
+		start := pos
+
+		// end-of-contents of the innermost constructed object
+		if b[pos] == 0x00 && len(stack) > 0 {
+			if pos+1 >= len(b) || b[pos+1] != 0x00 {
+				return &CERError{Offset: start, Err: errors.New("invalid end-of-contents")}
+			}
+
+			pos += 2
+
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if err := f.finish(); err != nil {
+				return &CERError{Offset: f.offset, Err: err}
+			}
+
+			if err := cerCompleted(stack, b, f.tag, f.offset, pos); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		tag, err := parseTag(b[pos:])
+		if err != nil {
+			return &CERError{Offset: start, Err: err}
+		}
+
+		if err = checkCERTag(tag); err != nil {
+			return &CERError{Offset: start, Err: err}
+		}
+
+		length, lLen, indefinite, err := parseCERLength(b[pos+len(tag):])
+		if err != nil {
+			return &CERError{Offset: start, Err: errors.Wrapf(err, "tag %02X", []byte(tag))}
+		}
+
+		pos += len(tag) + lLen
+
+		if len(stack) > 0 {
+			if err = stack[len(stack)-1].child(tag, b[pos:], length); err != nil {
+				return &CERError{Offset: start, Err: err}
+			}
+		}
+
+		if tag.IsConstructed() {
+			if !indefinite {
+				return &CERError{Offset: start, Err: errors.Errorf("tag %02X: constructed object must use the indefinite length form", []byte(tag))}
+			}
+
+			f := cerFrame{tag: tag, offset: start}
+
+			if tag.Class() == Universal {
+				switch number := tag.Number(); {
+				case number == UniversalSet:
+					f.set, f.sortedByTag, f.sortedByEncoding = true, true, true
+				case number == UniversalBitString:
+					f.segment = UniversalBitString
+				case isCERString(number):
+					f.segment = UniversalOctetString
+				case !isConstructedUniversal(number):
+					return &CERError{Offset: start, Err: errors.Errorf("tag %02X: universal type %d must be primitive", []byte(tag), number)}
+				}
+			}
+
+			stack = append(stack, f)
+
+			continue
+		}
+
+		if indefinite {
+			return &CERError{Offset: start, Err: errors.Errorf("tag %02X: primitive object must use the definite length form", []byte(tag))}
+		}
+
+		if length > len(b)-pos {
+			return &CERError{Offset: start, Err: errors.Errorf("tag %02X: indicated length of value is out of bounds", []byte(tag))}
+		}
+
+		if err = checkCERValue(BerTLV{Tag: tag, Value: b[pos : pos+length]}); err != nil {
+			return &CERError{Offset: start, Err: err}
+		}
+
+		pos += length
+
+		if err = cerCompleted(stack, b, tag, start, pos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cerCompleted checks the order of a completed component b[start:end] of a SET or SET OF. Since both have the same
+// tag, the components must either have distinct tags in ascending order like those of a SET or be sorted by their
+// encodings like those of a SET OF.
+func cerCompleted(stack []cerFrame, b []byte, tag BerTag, start int, end int) error {
+	if len(stack) == 0 || !stack[len(stack)-1].set {
+		return nil
+	}
+
+	f := &stack[len(stack)-1]
+
+	if f.prevTag != nil {
+		f.sortedByTag = f.sortedByTag && compareTags(f.prevTag, tag) < 0
+		f.sortedByEncoding = f.sortedByEncoding && compareEncodings(b[f.prevStart:f.prevEnd], b[start:end]) <= 0
+
+		if !f.sortedByTag && !f.sortedByEncoding {
+			return &CERError{Offset: start, Err: errors.Errorf("tag %02X: components of SET are not sorted", []byte(f.tag))}
+		}
+	}
+
+	f.prevTag, f.prevStart, f.prevEnd = tag, start, end
+
+	return nil
+}
+
+// child checks a child object of a string in constructed form, value is the remaining input after its header.
+func (f *cerFrame) child(tag BerTag, value []byte, length int) error {
+	if f.segment == 0 {
+		return nil
+	}
+
+	if tag.Class() != Universal || tag.IsConstructed() || tag.Number() != f.segment {
+		return errors.Errorf("tag %02X: segment has tag %02X, expected %02X", []byte(f.tag), []byte(tag), []byte(NewTag(Universal, false, f.segment)))
+	}
+
+	// only the last segment may be shorter or have unused bits
+	if f.segments > 0 {
+		if len(f.lastSegment) != CERSegmentSize {
+			return errors.Errorf("tag %02X: segment %d has %d contents bytes, expected %d", []byte(f.tag), f.segments-1, len(f.lastSegment), CERSegmentSize)
+		}
+
+		if f.segment == UniversalBitString && f.lastSegment[0] != 0x00 {
+			return errors.Errorf("tag %02X: segment %d has unused bits but is not the last segment", []byte(f.tag), f.segments-1)
+		}
+	}
+
+	if length > len(value) {
+		length = len(value)
+	}
+
+	f.segments++
+	f.lastSegment = value[:length]
+	f.contentLength += length
+
+	if f.segment == UniversalBitString && length > 0 {
+		// the number of unused bits is only counted once
+		f.contentLength--
+	}
+
+	return nil
+}
+
+// finish checks a constructed object after its end-of-contents.
+func (f *cerFrame) finish() error {
+	if f.segment == 0 {
+		return nil
+	}
+
+	contentLength := f.contentLength
+	if f.segment == UniversalBitString {
+		contentLength++
+	}
+
+	if contentLength <= CERSegmentSize {
+		return errors.Errorf("tag %02X: string with %d contents bytes must use the primitive form", []byte(f.tag), contentLength)
+	}
+
+	return nil
+}
+
+// checkCERTag checks that tag is encoded with the minimal number of bytes and is not the end-of-contents tag.
+func checkCERTag(tag BerTag) error {
+	if len(tag) == 1 && tag[0] == 0x00 {
+		return errors.New("unexpected end-of-contents")
+	}
+
+	if !bytes.Equal(NewTag(tag.Class(), tag.IsConstructed(), tag.Number()), tag) {
+		return errors.Errorf("tag %02X is not encoded with the minimal number of bytes", []byte(tag))
+	}
+
+	return nil
+}
+
+// parseCERLength parses a length field of up to four bytes or the indefinite length form and checks that definite
+// lengths are encoded with the minimal number of bytes.
+func parseCERLength(b []byte) (int, int, bool, error) {
+	if len(b) == 0 {
+		return 0, 0, false, errors.New("missing length")
+	}
+
+	switch {
+	case b[0] < 0x80:
+		return int(b[0]), 1, false, nil
+	case b[0] == 0x80:
+		return 0, 1, true, nil
+	}
+
+	n := int(b[0] & 0x7F)
+	if n > 4 {
+		return 0, 0, false, errors.Errorf("length field with %d subsequent bytes is not supported", n)
+	}
+
+	if len(b) <= n {
+		return 0, 0, false, errors.New("length field is truncated")
+	}
+
+	if b[1] == 0x00 {
+		return 0, 0, false, errors.New("length is not encoded with the minimal number of bytes")
+	}
+
+	length := 0
+	for _, l := range b[1 : n+1] {
+		length = length<<8 | int(l)
+	}
+
+	if length < 0x80 {
+		return 0, 0, false, errors.New("length is not encoded with the minimal number of bytes")
+	}
+
+	return length, n + 1, false, nil
+}
+
+// checkCERValue checks the value of a primitive object with a universal tag.
+func checkCERValue(ber BerTLV) error {
+	if ber.Tag.Class() != Universal {
+		return nil
+	}
+
+	number := ber.Tag.Number()
+
+	if isCERString(number) && len(ber.Value) > CERSegmentSize {
+		return errors.Errorf("tag %02X: string with %d contents bytes must use the constructed form", []byte(ber.Tag), len(ber.Value))
+	}
+
+	switch number {
+	case UniversalBoolean:
+		if len(ber.Value) != 1 || (ber.Value[0] != 0x00 && ber.Value[0] != 0xFF) {
+			return errors.Errorf("tag %02X: BOOLEAN must be encoded as 00 or FF", []byte(ber.Tag))
+		}
+	case UniversalInteger, UniversalEnumerated:
+		return ber.checkInteger()
+	case UniversalNull:
+		return ber.Null()
+	case UniversalObjectIdentifier:
+		_, err := ber.ObjectIdentifier()
+		return err
+	case UniversalRelativeOID:
+		_, err := ber.RelativeOID()
+		return err
+	case UniversalBitString:
+		bs, err := ber.BitString()
+		if err != nil {
+			return err
+		}
+
+		if unused := 8*len(bs.Bytes) - bs.BitLength; unused > 0 && bs.Bytes[len(bs.Bytes)-1]&^(0xFF<<uint(unused)) != 0 {
+			return errors.Errorf("tag %02X: unused bits of BIT STRING must be zero", []byte(ber.Tag))
+		}
+	case UniversalReal:
+		return checkCERReal(ber)
+	case UniversalUTCTime:
+		if len(ber.Value) != 13 || ber.Value[12] != 'Z' {
+			return errors.Errorf("tag %02X: UTCTime must have the format YYMMDDhhmmssZ", []byte(ber.Tag))
+		}
+
+		_, err := ber.UTCTime()
+		return err
+	case UniversalGeneralizedTime:
+		return checkCERGeneralizedTime(ber)
+	case UniversalUTF8String:
+		_, err := ber.UTF8String()
+		return err
+	case UniversalPrintableString:
+		_, err := ber.PrintableString()
+		return err
+	case UniversalIA5String:
+		_, err := ber.IA5String()
+		return err
+	}
+
+	return nil
+}
+
+// checkCERReal checks that a REAL is encoded in base 2 with an odd mantissa, in decimal NR3 form or as special value.
+func checkCERReal(ber BerTLV) error {
+	if _, err := ber.Real(); err != nil {
+		return err
+	}
+
+	if len(ber.Value) == 0 {
+		return nil
+	}
+
+	switch first := ber.Value[0]; {
+	case first&0x80 != 0:
+		if first&0x3C != 0 {
+			return errors.Errorf("tag %02X: binary REAL must use base 2 without scaling factor", []byte(ber.Tag))
+		}
+
+		if ber.Value[len(ber.Value)-1]&0x01 == 0 {
+			return errors.Errorf("tag %02X: mantissa of binary REAL must be odd", []byte(ber.Tag))
+		}
+	case first&0x40 == 0 && first != 0x03:
+		return errors.Errorf("tag %02X: decimal REAL must use the NR3 form", []byte(ber.Tag))
+	}
+
+	return nil
+}
+
+// checkCERGeneralizedTime checks that a GeneralizedTime has the format YYYYMMDDhhmmss[.f]Z without trailing zeros
+// in the fractional seconds.
+func checkCERGeneralizedTime(ber BerTLV) error {
+	v := ber.Value
+
+	if len(v) < 15 || v[len(v)-1] != 'Z' || (v[14] != 'Z' && v[14] != '.') {
+		return errors.Errorf("tag %02X: GeneralizedTime must have the format YYYYMMDDhhmmss[.f]Z", []byte(ber.Tag))
+	}
+
+	if v[14] == '.' && v[len(v)-2] == '0' {
+		return errors.Errorf("tag %02X: fractional seconds of GeneralizedTime must not have trailing zeros", []byte(ber.Tag))
+	}
+
+	_, err := ber.GeneralizedTime()
+
+	return err
+}
@@ -0,0 +1,321 @@
+package bertlv
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"testing"
+)
+
+// cerString returns a primitive string with the given tag and n contents bytes.
+func cerString(tag byte, n int) []byte {
+	b := appendLen([]byte{tag}, n)
+
+	for i := 0; i < n; i++ {
+		b = append(b, byte(i))
+	}
+
+	return b
+}
+
+func TestBerTLV_Encode_CER(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		{
+			name:     "sequence",
+			input:    []byte{0x30, 0x07, 0x02, 0x01, 0x01, 0x04, 0x02, 0x01, 0x02},
+			expected: []byte{0x30, 0x80, 0x02, 0x01, 0x01, 0x04, 0x02, 0x01, 0x02, 0x00, 0x00},
+		},
+		{
+			name:     "nested",
+			input:    []byte{0xA0, 0x05, 0x30, 0x03, 0x02, 0x01, 0x05},
+			expected: []byte{0xA0, 0x80, 0x30, 0x80, 0x02, 0x01, 0x05, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name:     "set, sorted by tag",
+			input:    []byte{0x31, 0x09, 0x80, 0x01, 0x01, 0x04, 0x01, 0xAA, 0x02, 0x01, 0x01},
+			expected: []byte{0x31, 0x80, 0x02, 0x01, 0x01, 0x04, 0x01, 0xAA, 0x80, 0x01, 0x01, 0x00, 0x00},
+		},
+		{
+			name:     "set of, sorted by encoding",
+			input:    []byte{0x31, 0x09, 0x04, 0x01, 0x02, 0x04, 0x02, 0x01, 0x01, 0x04, 0x00},
+			expected: []byte{0x31, 0x80, 0x04, 0x00, 0x04, 0x01, 0x02, 0x04, 0x02, 0x01, 0x01, 0x00, 0x00},
+		},
+		{
+			name:     "short constructed octet string becomes primitive",
+			input:    []byte{0x24, 0x06, 0x04, 0x01, 0x01, 0x04, 0x01, 0x02},
+			expected: []byte{0x04, 0x02, 0x01, 0x02},
+		},
+		{
+			name:     "short constructed utf8 string becomes primitive",
+			input:    []byte{0x2C, 0x06, 0x04, 0x01, 0x61, 0x04, 0x01, 0x62},
+			expected: []byte{0x0C, 0x02, 0x61, 0x62},
+		},
+		{
+			name:     "unused bits of bit string are cleared",
+			input:    []byte{0x03, 0x02, 0x04, 0xFF},
+			expected: []byte{0x03, 0x02, 0x04, 0xF0},
+		},
+		{
+			name:     "set of with mixed tags, sorted by encoding",
+			input:    []byte{0x31, 0x0B, 0xA0, 0x03, 0x02, 0x01, 0x01, 0x81, 0x01, 0x02, 0x81, 0x01, 0x01},
+			expected: []byte{0x31, 0x80, 0x81, 0x01, 0x01, 0x81, 0x01, 0x02, 0xA0, 0x80, 0x02, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name:     "boolean",
+			input:    []byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x00},
+			expected: []byte{0x01, 0x01, 0xFF, 0x01, 0x01, 0x00},
+		},
+		{
+			name:     "non-minimal integer",
+			input:    []byte{0x02, 0x03, 0x00, 0x00, 0x7F, 0x0A, 0x02, 0xFF, 0x80},
+			expected: []byte{0x02, 0x01, 0x7F, 0x0A, 0x01, 0x80},
+		},
+		{
+			name:     "implicitly tagged values are kept",
+			input:    []byte{0x81, 0x02, 0x00, 0x01},
+			expected: []byte{0x81, 0x02, 0x00, 0x01},
+		},
+		{
+			name:     "non-minimal tag",
+			input:    []byte{0x1F, 0x02, 0x01, 0x05},
+			expected: []byte{0x02, 0x01, 0x05},
+		},
+		{
+			name:     "utc time",
+			input:    append([]byte{0x17, 0x0F}, "1903041214+0100"...),
+			expected: append([]byte{0x17, 0x0D}, "190304111400Z"...),
+		},
+		{
+			name:     "generalized time",
+			input:    append([]byte{0x18, 0x17}, "20500101000000,100-0130"...),
+			expected: append([]byte{0x18, 0x11}, "20500101013000.1Z"...),
+		},
+		{
+			name:     "generalized time without fraction",
+			input:    append([]byte{0x18, 0x13}, "20500101000000.000Z"...),
+			expected: append([]byte{0x18, 0x0F}, "20500101000000Z"...),
+		},
+		{
+			name:     "real in base 16 with scaling factor",
+			input:    []byte{0x09, 0x03, 0xA4, 0x01, 0x03},
+			expected: []byte{0x09, 0x03, 0x80, 0x05, 0x03},
+		},
+		{
+			name:     "real with even mantissa",
+			input:    []byte{0x09, 0x03, 0x80, 0x00, 0x02},
+			expected: []byte{0x09, 0x03, 0x80, 0x01, 0x01},
+		},
+		{
+			name:     "non-minimal length",
+			input:    []byte{0x81, 0x81, 0x01, 0xAA},
+			expected: []byte{0x81, 0x01, 0xAA},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tlvs, err := Parse(tc.input)
+			if err != nil {
+				t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+			}
+
+			received := tlvs.Encode(CEREncoding)
+			if !bytes.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_EncodeCER_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input BerTLVs
+	}{
+		{name: "invalid bit string", input: BerTLVs{{Tag: NewOneByteTag(0x03), Value: []byte{0x04}}}},
+		{name: "invalid boolean", input: BerTLVs{{Tag: NewOneByteTag(0x01), Value: []byte{0x01, 0x01}}}},
+		{name: "empty integer", input: BerTLVs{{Tag: NewOneByteTag(0x02)}}},
+		{name: "invalid null", input: BerTLVs{{Tag: NewOneByteTag(0x05), Value: []byte{0x00}}}},
+		{name: "invalid utf8 string", input: BerTLVs{{Tag: NewOneByteTag(0x0C), Value: []byte{0xFF}}}},
+		{name: "decimal real in NR1 form", input: BerTLVs{{Tag: NewOneByteTag(0x09), Value: []byte{0x01, '1'}}}},
+		{name: "binary real that requires rounding", input: BerTLVs{{Tag: NewOneByteTag(0x09), Value: append([]byte{0x80, 0x00}, append(bytes.Repeat([]byte{0xFF}, 7), 0xFE)...)}}},
+		{name: "utc time out of range", input: BerTLVs{{Tag: NewOneByteTag(0x17), Value: []byte("491231233000-0100")}}},
+		{name: "constructed integer", input: BerTLVs{{Tag: NewOneByteTag(0x22)}}},
+		{name: "empty tag", input: BerTLVs{{Value: []byte{0x01}}}},
+		{name: "end-of-contents tag", input: BerTLVs{{Tag: NewOneByteTag(0x00)}}},
+		{name: "invalid nested value", input: BerTLVs{{Tag: NewOneByteTag(0x30), Value: []byte{0x02, 0x05}}}},
+		{name: "invalid component of set", input: BerTLVs{{Tag: NewOneByteTag(0x31), Value: []byte{0x01, 0x00}}}},
+		{name: "long value", input: BerTLVs{{Tag: NewOneByteTag(0x81), Value: make([]byte, 65536)}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := tc.input.EncodeCER()
+			if err == nil {
+				t.Fatalf("Expected: error, got: '%X'", received)
+			}
+
+			if encoded := tc.input.Encode(CEREncoding); encoded != nil {
+				t.Errorf("Expected: nil, got: '%X'", encoded)
+			}
+		})
+	}
+}
+
+func TestBerTLV_EncodeCER_RoundTrip(t *testing.T) {
+	inputs := [][]byte{
+		{0x30, 0x09, 0x01, 0x01, 0x01, 0x02, 0x04, 0x00, 0x00, 0x00, 0x01},
+		{0x31, 0x0B, 0x81, 0x01, 0x02, 0xA0, 0x03, 0x02, 0x01, 0xFF, 0x81, 0x01, 0x01},
+		{0x31, 0x0A, 0xA0, 0x03, 0x02, 0x01, 0x01, 0x81, 0x01, 0x01, 0x05, 0x00},
+		{0x24, 0x08, 0x04, 0x02, 0x01, 0x02, 0x24, 0x02, 0x04, 0x00},
+		{0x03, 0x03, 0x03, 0xFF, 0xFF},
+		{0x06, 0x03, 0x2A, 0x86, 0x48, 0x0D, 0x01, 0x00},
+		{0x09, 0x03, 0x90, 0x02, 0x04, 0x09, 0x02, 0x03, '1', 0x09, 0x01, 0x43},
+		append([]byte{0x18, 0x16}, "20190304121415.10+0100"...),
+		append([]byte{0x18, 0x0A}, "2019030412"...),
+		append([]byte{0x17, 0x0B}, "1903041214Z"...),
+		{0x5F, 0x20, 0x01, 0x41, 0x1F, 0x1E, 0x00},
+	}
+
+	for _, input := range inputs {
+		tlvs, err := Parse(input)
+		if err != nil {
+			t.Fatalf("Expected: no error for '%X', got: error(%v)", input, err.Error())
+		}
+
+		cer, err := tlvs.EncodeCER()
+		if err != nil {
+			t.Errorf("Expected: no error for '%X', got: error(%v)", input, err.Error())
+			continue
+		}
+
+		if err = ValidateCER(cer); err != nil {
+			t.Errorf("Expected: valid CER for '%X', got: '%X', error(%v)", input, cer, err.Error())
+		}
+	}
+}
+
+func TestBerTLV_Encode_CER_Segments(t *testing.T) {
+	long := make([]byte, 2500)
+	for i := range long {
+		long[i] = byte(i)
+	}
+
+	tlvs, err := (&Builder{}).
+		AddOctetString(NewOneByteTag(0x04), long).
+		AddBitString(NewOneByteTag(0x03), asn1.BitString{Bytes: long[:1500], BitLength: 8*1500 - 3}).
+		AddOctetString(NewOneByteTag(0x04), long[:1000]).
+		AddUTF8String(NewOneByteTag(0x0C), string(bytes.Repeat([]byte{'a'}, 1001))).
+		BuildBerTLVs()
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	received := tlvs.Encode(CEREncoding)
+
+	if err = ValidateCER(received); err != nil {
+		t.Fatalf("Expected: valid CER, got: error(%v)", err.Error())
+	}
+
+	expectedPrefix := []byte{0x24, 0x80, 0x04, 0x82, 0x03, 0xE8, 0x00, 0x01}
+	if !bytes.HasPrefix(received, expectedPrefix) {
+		t.Errorf("Expected prefix: '%X', got: '%X'", expectedPrefix, received[:len(expectedPrefix)])
+	}
+
+	// octet string: 2 header bytes, 3 segments, end-of-contents
+	pos := 2 + 2*(4+1000) + 4 + 500 + 2
+
+	// bit string: 2 header bytes, 999 data bytes and the unused bits in the first segment
+	expectedBitString := []byte{0x23, 0x80, 0x03, 0x82, 0x03, 0xE8, 0x00, 0x00}
+	if !bytes.Equal(received[pos:pos+len(expectedBitString)], expectedBitString) {
+		t.Errorf("Expected: '%X', got: '%X'", expectedBitString, received[pos:pos+len(expectedBitString)])
+	}
+
+	// last segment of the bit string: 501 data bytes with 3 unused bits
+	pos += 2 + 4 + 1000
+	expectedLastSegment := []byte{0x03, 0x82, 0x01, 0xF6, 0x03}
+	if !bytes.Equal(received[pos:pos+len(expectedLastSegment)], expectedLastSegment) {
+		t.Errorf("Expected: '%X', got: '%X'", expectedLastSegment, received[pos:pos+len(expectedLastSegment)])
+	}
+
+	// 1000 bytes are encoded in primitive form
+	pos += 4 + 502 + 2
+	if !bytes.HasPrefix(received[pos:], []byte{0x04, 0x82, 0x03, 0xE8}) {
+		t.Errorf("Expected: primitive octet string, got: '%X'", received[pos:pos+4])
+	}
+
+	// segments of character strings are OCTET STRINGs
+	pos += 4 + 1000
+	if !bytes.HasPrefix(received[pos:], []byte{0x2C, 0x80, 0x04, 0x82, 0x03, 0xE8}) {
+		t.Errorf("Expected: constructed UTF8String, got: '%X'", received[pos:pos+6])
+	}
+}
+
+func TestValidateCER(t *testing.T) {
+	longString := append(cerString(0x04, 1000), cerString(0x04, 1)...)
+
+	tests := []struct {
+		name           string
+		input          []byte
+		expectedOffset int
+		expectError    bool
+	}{
+		{name: "sequence", input: []byte{0x30, 0x80, 0x02, 0x01, 0x01, 0x04, 0x02, 0x01, 0x02, 0x00, 0x00}},
+		{name: "multiple objects", input: []byte{0x01, 0x01, 0xFF, 0x05, 0x00, 0x30, 0x80, 0x00, 0x00}},
+		{name: "set, sorted", input: []byte{0x31, 0x80, 0x02, 0x01, 0x01, 0x04, 0x01, 0xAA, 0x80, 0x01, 0x01, 0x00, 0x00}},
+		{name: "set of, sorted", input: []byte{0x31, 0x80, 0x04, 0x01, 0x01, 0x04, 0x01, 0x01, 0x04, 0x02, 0x01, 0x01, 0x00, 0x00}},
+		{name: "set with mixed forms, sorted by tag", input: []byte{0x31, 0x80, 0xA0, 0x80, 0x02, 0x01, 0x01, 0x00, 0x00, 0x81, 0x01, 0x01, 0x00, 0x00}},
+		{name: "set of with mixed tags, sorted by encoding", input: []byte{0x31, 0x80, 0x81, 0x01, 0x01, 0x81, 0x01, 0x02, 0xA0, 0x80, 0x02, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00}},
+		{name: "segmented octet string", input: append(append([]byte{0x24, 0x80}, longString...), 0x00, 0x00)},
+		{name: "times", input: append(append([]byte{0x17, 0x0D}, "190304121415Z"...), append([]byte{0x18, 0x12}, "20500101000000.12Z"...)...)},
+		{name: "real", input: []byte{0x09, 0x03, 0x80, 0xFE, 0x03, 0x09, 0x00, 0x09, 0x01, 0x40}},
+		{name: "long tag", input: []byte{0x5F, 0x20, 0x01, 0x41}},
+		{name: "Error: empty", input: nil, expectError: true},
+		{name: "Error: constructed with definite length", input: []byte{0x30, 0x03, 0x02, 0x01, 0x01}, expectError: true},
+		{name: "Error: primitive with indefinite length", input: []byte{0x04, 0x80, 0x00, 0x00}, expectError: true},
+		{name: "Error: non-minimal length", input: []byte{0x30, 0x80, 0x04, 0x81, 0x01, 0xAA, 0x00, 0x00}, expectedOffset: 2, expectError: true},
+		{name: "Error: non-minimal long length", input: []byte{0x04, 0x82, 0x00, 0x81}, expectError: true},
+		{name: "Error: non-minimal tag", input: []byte{0x1F, 0x05, 0x00}, expectError: true},
+		{name: "Error: missing end-of-contents", input: []byte{0x30, 0x80, 0x02, 0x01, 0x01}, expectError: true},
+		{name: "Error: invalid end-of-contents", input: []byte{0x30, 0x80, 0x00, 0x01}, expectedOffset: 2, expectError: true},
+		{name: "Error: end-of-contents on top level", input: []byte{0x00, 0x00}, expectError: true},
+		{name: "Error: out of bounds", input: []byte{0x04, 0x03, 0x01}, expectError: true},
+		{name: "Error: boolean", input: []byte{0x30, 0x80, 0x01, 0x01, 0x01, 0x00, 0x00}, expectedOffset: 2, expectError: true},
+		{name: "Error: integer", input: []byte{0x02, 0x02, 0x00, 0x01}, expectError: true},
+		{name: "Error: null", input: []byte{0x05, 0x01, 0x00}, expectError: true},
+		{name: "Error: constructed integer", input: []byte{0x22, 0x80, 0x00, 0x00}, expectError: true},
+		{name: "Error: set, not sorted", input: []byte{0x31, 0x80, 0x04, 0x01, 0xAA, 0x02, 0x01, 0x01, 0x00, 0x00}, expectedOffset: 5, expectError: true},
+		{name: "Error: set of, not sorted", input: []byte{0x31, 0x80, 0x04, 0x01, 0x02, 0x04, 0x01, 0x01, 0x00, 0x00}, expectedOffset: 5, expectError: true},
+		{name: "Error: set of with mixed tags, sorted by tag", input: []byte{0x31, 0x80, 0xA0, 0x80, 0x02, 0x01, 0x01, 0x00, 0x00, 0x81, 0x01, 0x02, 0x81, 0x01, 0x01, 0x00, 0x00}, expectedOffset: 12, expectError: true},
+		{name: "Error: short constructed string", input: []byte{0x24, 0x80, 0x04, 0x01, 0x01, 0x00, 0x00}, expectError: true},
+		{name: "Error: long primitive string", input: cerString(0x04, 1001), expectError: true},
+		{name: "Error: short segment", input: append(append([]byte{0x24, 0x80}, append(cerString(0x04, 999), cerString(0x04, 2)...)...), 0x00, 0x00), expectedOffset: 1005, expectError: true},
+		{name: "Error: constructed segment", input: []byte{0x24, 0x80, 0x24, 0x80, 0x00, 0x00, 0x00, 0x00}, expectedOffset: 2, expectError: true},
+		{name: "Error: segment tag", input: []byte{0x2C, 0x80, 0x0C, 0x01, 0x61, 0x00, 0x00}, expectedOffset: 2, expectError: true},
+		{name: "Error: unused bits not zero", input: []byte{0x03, 0x02, 0x04, 0xFF}, expectError: true},
+		{name: "Error: utc time without seconds", input: append([]byte{0x17, 0x0B}, "1903041214Z"...), expectError: true},
+		{name: "Error: generalized time with trailing zero", input: append([]byte{0x18, 0x12}, "20500101000000.10Z"...), expectError: true},
+		{name: "Error: generalized time with time difference", input: append([]byte{0x18, 0x13}, "20500101000000+0100"...), expectError: true},
+		{name: "Error: real in base 8", input: []byte{0x09, 0x03, 0x90, 0x02, 0x03}, expectError: true},
+		{name: "Error: real with even mantissa", input: []byte{0x09, 0x03, 0x80, 0x00, 0x02}, expectError: true},
+		{name: "Error: printable string", input: []byte{0x13, 0x01, 0x2A}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCER(tc.input)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			var cerErr *CERError
+			if errors.As(err, &cerErr) && cerErr.Offset != tc.expectedOffset {
+				t.Errorf("Expected: offset %d, got: %d (%v)", tc.expectedOffset, cerErr.Offset, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package bertlv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dumpBytesPerLine = 16
+	dumpPrefixWidth  = 31 // width of offset, depth, header length, length and form columns
+)
+
+// DumpOptions configures the output of Dump.
+type DumpOptions struct {
+	// TagName optionally returns a name for a tag (e.g. from a registry of tags) which is printed next to the tag.
+	// Empty names are omitted.
+	TagName func(tag BerTag) string
+	// Form optionally overrides whether the value of an object is dumped as nested objects, see ParseOptions.Form.
+	Form func(tag BerTag) Form
+	// Heuristic optionally enables heuristic decoding of primitive values, see BerTLVs.DecodeHeuristic.
+	// Objects that were found by heuristic decoding are marked with '?' instead of ':' after 'prim' or 'cons'.
+	Heuristic *HeuristicOptions
+	// Redaction is the optional policy for masking values of sensitive objects, e.g. DefaultRedactionPolicy().
+	// The values of redacted constructed objects are dumped masked as a whole instead of as nested objects.
+	Redaction RedactionPolicy
+}
+
+// Dump writes an annotated hexdump of BER-TLV encoded bytes to w, similar to 'openssl asn1parse -i'.
+// Each BER-TLV object is printed on one line containing its offset, depth, header length, value length,
+// whether it is constructed or primitive and its tag and length bytes indented by depth.
+// Values of primitive objects are printed below as hex and ASCII with 16 bytes per line.
+//
+// Invalid input is dumped up to the first error. The error is then printed as last line and returned.
+func Dump(w io.Writer, b []byte, opts DumpOptions) error {
+	bw := bufio.NewWriter(w)
+
+	dumpErr := dump(bw, b, opts)
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s: write dump", packageTag))
+	}
+
+	return dumpErr
+}
+
+// Dump writes an annotated hexdump of the encoded BerTLVs to w. The original encoding of parsed objects is kept,
+// so offsets refer to the input the BerTLVs were parsed from as long as all objects were parsed from the same input
+// and none of them have been modified. See Dump for details.
+func (t BerTLVs) Dump(w io.Writer, opts DumpOptions) error {
+	return Dump(w, t.Encode(PreserveEncoding), opts)
+}
+
+func dump(w io.Writer, b []byte, opts DumpOptions) error {
+	if len(b) == 0 {
+		return errors.Errorf("%s: TLV has length 0", packageTag)
+	}
+
+	// frames holds the constructed objects or decoded primitive objects the current position is nested in,
+	// the last element is the innermost object.
+	frames := []dumpFrame{{end: len(b)}}
+
+	for pos := 0; pos < len(b); {
+		for len(frames) > 1 && pos == frames[len(frames)-1].end {
+			frames = frames[:len(frames)-1]
+		}
+
+		depth := len(frames) - 1
+		parent := frames[depth]
+
+		tag, length, hLen, err := parseHeader(b[pos:parent.end])
+		if err != nil {
+			fmt.Fprintf(w, "%5d: error: %s\n", pos, err)
+
+			return errors.Wrap(err, fmt.Sprintf("%s: invalid TLV starting at index %d", packageTag, pos))
+		}
+
+		constructed := isConstructed(tag, opts.Form)
+
+		kind := "prim"
+		if constructed {
+			kind = "cons"
+		}
+
+		separator := ':'
+		if parent.heuristic {
+			separator = '?'
+		}
+
+		fmt.Fprintf(w, "%5d: d=%-2d hl=%d l=%5d %s%c %s% X", pos, depth, hLen, length, kind, separator, strings.Repeat("  ", depth), b[pos:pos+hLen])
+
+		if opts.TagName != nil {
+			if name := opts.TagName(tag); name != "" {
+				fmt.Fprintf(w, " (%s)", name)
+			}
+		}
+
+		fmt.Fprintln(w)
+
+		valueStart := pos + hLen
+		valueEnd := valueStart + length
+
+		if r, ok := opts.Redaction[tag.ID()]; ok {
+			dumpValue(w, b[valueStart:valueEnd], depth, &r)
+
+			pos = valueEnd
+
+			continue
+		}
+
+		if constructed && length > 0 {
+			frames = append(frames, dumpFrame{end: valueEnd, heuristic: parent.heuristic})
+			pos = valueStart
+
+			continue
+		}
+
+		if opts.Heuristic != nil {
+			if _, ok := opts.Heuristic.decode(b[valueStart:valueEnd], valueStart); ok {
+				frames = append(frames, dumpFrame{end: valueEnd, heuristic: true})
+				pos = valueStart
+
+				continue
+			}
+		}
+
+		dumpValue(w, b[valueStart:valueEnd], depth, nil)
+
+		pos = valueEnd
+	}
+
+	return nil
+}
+
+// dumpFrame is an object whose value is dumped as nested objects.
+type dumpFrame struct {
+	end       int  // Offset of the first byte following the value.
+	heuristic bool // The value was decoded heuristically or is nested in such a value.
+}
+
+// dumpValue dumps v as hex and ASCII. If r is not nil, v is masked accordingly.
+func dumpValue(w io.Writer, v []byte, depth int, r *Redaction) {
+	indent := strings.Repeat(" ", dumpPrefixWidth) + strings.Repeat("  ", depth+1)
+
+	digits := []byte(fmt.Sprintf("%X", v))
+	if r != nil {
+		r.mask(digits)
+	}
+
+	for i := 0; i < len(v); i += dumpBytesPerLine {
+		n := len(v) - i
+		if n > dumpBytesPerLine {
+			n = dumpBytesPerLine
+		}
+
+		hex := make([]string, n)
+		ascii := []byte(printable(v[i : i+n]))
+
+		for j := range hex {
+			hex[j] = string(digits[2*(i+j) : 2*(i+j)+2])
+
+			if strings.IndexByte(hex[j], '*') >= 0 {
+				ascii[j] = '*'
+			}
+		}
+
+		fmt.Fprintf(w, "%s%-*s  |%s|\n", indent, dumpBytesPerLine*3-1, strings.Join(hex, " "), ascii)
+	}
+}
+
+func printable(b []byte) string {
+	r := make([]byte, len(b))
+
+	for i, c := range b {
+		if c < 0x20 || c > 0x7E {
+			c = '.'
+		}
+
+		r[i] = c
+	}
+
+	return string(r)
+}
@@ -0,0 +1,159 @@
+package bertlv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		opts        DumpOptions
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:  "nested constructed",
+			input: []byte{0x71, 0x09, 0xB0, 0x07, 0x0F, 0x05, 0x41, 0x42, 0x43, 0x00, 0xFF},
+			expected: []string{
+				"    0: d=0  hl=2 l=    9 cons: 71 09",
+				"    2: d=1  hl=2 l=    7 cons:   B0 07",
+				"    4: d=2  hl=2 l=    5 prim:     0F 05",
+				"                                     41 42 43 00 FF                                   |ABC..|",
+			},
+		},
+		{
+			name:  "tag name, value longer than one line",
+			input: append([]byte{0x5F, 0x20, 0x11}, []byte("0123456789ABCDEFG")...),
+			opts: DumpOptions{TagName: func(tag BerTag) string {
+				if bytes.Equal(tag, NewTwoByteTag(0x5F, 0x20)) {
+					return "Cardholder Name"
+				}
+
+				return ""
+			}},
+			expected: []string{
+				"    0: d=0  hl=3 l=   17 prim: 5F 20 11 (Cardholder Name)",
+				"                                 30 31 32 33 34 35 36 37 38 39 41 42 43 44 45 46  |0123456789ABCDEF|",
+				"                                 47                                               |G|",
+			},
+		},
+		{
+			name:  "redacted",
+			input: []byte{0x70, 0x0E, 0x5A, 0x08, 0x54, 0x13, 0x33, 0x00, 0x89, 0x01, 0x04, 0x34, 0x5F, 0x20, 0x01, 0x41},
+			opts:  DumpOptions{Redaction: DefaultRedactionPolicy()},
+			expected: []string{
+				"    0: d=0  hl=2 l=   14 cons: 70 0E",
+				"    2: d=1  hl=2 l=    8 prim:   5A 08",
+				"                                   54 13 33 ** ** ** 04 34                          |T.3***.4|",
+				"   12: d=1  hl=3 l=    1 prim:   5F 20 01",
+				"                                   **                                               |*|",
+			},
+		},
+		{
+			name:  "redacted constructed",
+			input: []byte{0x70, 0x05, 0x80, 0x01, 0x01, 0x90, 0x00, 0x91, 0x01, 0x02},
+			opts:  DumpOptions{Redaction: RedactionPolicy{0x70: {KeepFirst: 2}}},
+			expected: []string{
+				"    0: d=0  hl=2 l=    5 cons: 70 05",
+				"                                 80 ** ** ** **                                   |.****|",
+				"    7: d=0  hl=2 l=    1 prim: 91 01",
+				"                                 02                                               |.|",
+			},
+		},
+		{
+			name:  "form override",
+			input: []byte{0x90, 0x03, 0x80, 0x01, 0x41},
+			opts: DumpOptions{Form: func(tag BerTag) Form {
+				if bytes.Equal(tag, NewOneByteTag(0x90)) {
+					return FormConstructed
+				}
+
+				return FormDefault
+			}},
+			expected: []string{
+				"    0: d=0  hl=2 l=    3 cons: 90 03",
+				"    2: d=1  hl=2 l=    1 prim:   80 01",
+				"                                   41                                               |A|",
+			},
+		},
+		{
+			name:  "empty constructed",
+			input: []byte{0x70, 0x00, 0x90, 0x00},
+			expected: []string{
+				"    0: d=0  hl=2 l=    0 cons: 70 00",
+				"    2: d=0  hl=2 l=    0 prim: 90 00",
+			},
+		},
+		{
+			name:  "Error: dumped up to first error",
+			input: []byte{0x70, 0x05, 0x90, 0x01, 0xFF, 0x91, 0x05},
+			expected: []string{
+				"    0: d=0  hl=2 l=    5 cons: 70 05",
+				"    2: d=1  hl=2 l=    1 prim:   90 01",
+				"                                   FF                                               |.|",
+				"    5: error: tag 91: indicated length of value is out of bounds - indicated end index: 6 actual end index 1",
+			},
+			expectError: true,
+		},
+		{
+			name:        "Error: empty",
+			input:       nil,
+			expected:    nil,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			err := Dump(buf, tc.input, tc.opts)
+			if err != nil && !tc.expectError {
+				t.Errorf("Expected: no error, got: error(%v)", err.Error())
+
+				return
+			}
+
+			if err == nil && tc.expectError {
+				t.Errorf("Expected: error, got: no error")
+
+				return
+			}
+
+			expected := ""
+			if len(tc.expected) > 0 {
+				expected = strings.Join(tc.expected, "\n") + "\n"
+			}
+
+			if received := buf.String(); received != expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLVs_Dump(t *testing.T) {
+	input := []byte{0x70, 0x81, 0x03, 0x90, 0x01, 0xFF}
+
+	tlvs, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err = tlvs.Dump(buf, DumpOptions{}); err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	expected := "    0: d=0  hl=3 l=    3 cons: 70 81 03\n" +
+		"    3: d=1  hl=2 l=    1 prim:   90 01\n" +
+		"                                   FF                                               |.|\n"
+
+	if received := buf.String(); received != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, received)
+	}
+}
@@ -0,0 +1,100 @@
+package bertlv
+
+// HeuristicOptions configures the heuristic decoding of primitive values that may contain nested BER-TLV objects.
+//
+// A value is only decoded if it consists entirely of correctly encoded BER-TLV objects. Since short values often
+// happen to look like BER-TLV encoded data, the thresholds should be chosen to keep false positives low.
+type HeuristicOptions struct {
+	// MinValueLength is the minimum length of a value that is decoded.
+	MinValueLength int
+	// MaxObjects is the maximum number of top level objects a value may be decoded to, zero for no limit.
+	// Random bytes that happen to decode often result in many tiny objects.
+	MaxObjects int
+	// MaxDepth is the maximum nesting depth of the decoded objects, see ParseOptions.MaxDepth.
+	MaxDepth int
+}
+
+// DefaultHeuristicOptions are thresholds that work well for data of smart cards.
+var DefaultHeuristicOptions = HeuristicOptions{
+	MinValueLength: 4,
+	MaxObjects:     32,
+	MaxDepth:       16,
+}
+
+// DecodeHeuristic tries to decode the value of each primitive BerTLV, including child objects and decoded objects,
+// as a sequence of BER-TLV objects. If a value can be decoded, the result is attached as speculative children that
+// can be retrieved with BerTLV.Speculative. Children returned by BerTLV.Children are not changed.
+//
+// Decoded objects must have correctly encoded tags (see BerTag.CheckEncoding) and must not have tag '00',
+// which is used as padding by ISO/IEC 7816-4. The value of decoded objects must end exactly where the decoded value
+// ends.
+//
+// This is meant as an aid for the analysis of unknown data: a successfully decoded value is not necessarily
+// BER-TLV encoded. DecodeHeuristic returns a copy and does not modify BerTLVs.
+func (t BerTLVs) DecodeHeuristic(opts HeuristicOptions) BerTLVs {
+	if t == nil {
+		return nil
+	}
+
+	return opts.apply(t)
+}
+
+// Speculative returns the objects that DecodeHeuristic found in the value of a primitive BerTLV.
+// Returns nil if DecodeHeuristic was not called or the value could not be decoded.
+func (ber BerTLV) Speculative() BerTLVs {
+	return ber.speculative
+}
+
+func (o HeuristicOptions) apply(tlvs []BerTLV) []BerTLV {
+	result := make([]BerTLV, len(tlvs))
+
+	for i, tlv := range tlvs {
+		switch {
+		case tlv.unparsed != nil:
+		case tlv.children != nil:
+			tlv.children = o.apply(tlv.children)
+		default:
+			offset := 0
+			if span, ok := tlv.Span(); ok {
+				offset = span.ValueOffset
+			}
+
+			if speculative, ok := o.decode(tlv.Value, offset); ok {
+				tlv.speculative = o.apply(speculative)
+			}
+		}
+
+		result[i] = tlv
+	}
+
+	return result
+}
+
+// decode decodes value as sequence of BER-TLV objects if it meets the thresholds.
+// offset is the position of value in the input.
+func (o HeuristicOptions) decode(value []byte, offset int) ([]BerTLV, bool) {
+	if len(value) == 0 || len(value) < o.MinValueLength {
+		return nil, false
+	}
+
+	p := parser{opts: ParseOptions{MaxDepth: o.MaxDepth}}
+
+	tlvs, _, err := p.parse(value, offset, false)
+	if err != nil || (o.MaxObjects > 0 && len(tlvs) > o.MaxObjects) {
+		return nil, false
+	}
+
+	// check the tags of all decoded objects
+	for pending := append([]BerTLV{}, tlvs...); len(pending) > 0; {
+		tlv := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if tlv.Tag.CheckEncoding() != nil || tlv.Tag[0] == 0x00 {
+			return nil, false
+		}
+
+		pending = append(pending, tlv.children...)
+	}
+
+	return tlvs, true
+}
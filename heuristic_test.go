@@ -0,0 +1,127 @@
+package bertlv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBerTLVs_DecodeHeuristic(t *testing.T) {
+	tests := []struct {
+		name       string
+		inputBytes []byte
+		opts       HeuristicOptions
+		expected   []BerTLVs // speculative children of the top level objects
+	}{
+		{
+			name:       "primitive value contains TLVs",
+			inputBytes: []byte{0xC1, 0x06, 0x90, 0x01, 0xFF, 0x91, 0x01, 0xEE},
+			opts:       DefaultHeuristicOptions,
+			expected: []BerTLVs{
+				{
+					{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+					{Tag: NewOneByteTag(0x91), Value: []byte{0xEE}},
+				},
+			},
+		},
+		{
+			name:       "value too short",
+			inputBytes: []byte{0xC1, 0x03, 0x90, 0x01, 0xFF},
+			opts:       DefaultHeuristicOptions,
+			expected:   []BerTLVs{nil},
+		},
+		{
+			name:       "value does not end with object",
+			inputBytes: []byte{0xC1, 0x05, 0x90, 0x01, 0xFF, 0x91, 0x01},
+			opts:       DefaultHeuristicOptions,
+			expected:   []BerTLVs{nil},
+		},
+		{
+			name:       "zero tag",
+			inputBytes: []byte{0xC1, 0x04, 0x00, 0x00, 0x00, 0x00},
+			opts:       DefaultHeuristicOptions,
+			expected:   []BerTLVs{nil},
+		},
+		{
+			name:       "too many objects",
+			inputBytes: []byte{0xC1, 0x06, 0x01, 0x00, 0x02, 0x00, 0x03, 0x00},
+			opts:       HeuristicOptions{MaxObjects: 2},
+			expected:   []BerTLVs{nil},
+		},
+		{
+			name:       "constructed and empty objects are not decoded",
+			inputBytes: []byte{0x70, 0x06, 0xC1, 0x04, 0x5A, 0x02, 0x12, 0x34, 0x90, 0x00},
+			opts:       DefaultHeuristicOptions,
+			expected:   []BerTLVs{nil, nil},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := Parse(tc.inputBytes)
+			if err != nil {
+				t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+			}
+
+			received := parsed.DecodeHeuristic(tc.opts)
+
+			if len(received) != len(tc.expected) {
+				t.Fatalf("Expected: %d BerTLVs, got: %d", len(tc.expected), len(received))
+			}
+
+			for i := range received {
+				if !cmp.Equal(received[i].Speculative(), tc.expected[i], cmpParsed) {
+					t.Errorf("Expected: '%v', got: '%v'", tc.expected[i], received[i].Speculative())
+				}
+
+				if parsed[i].Speculative() != nil {
+					t.Errorf("Expected: input not modified")
+				}
+			}
+		})
+	}
+}
+
+func TestBerTLVs_DecodeHeuristic_Nested(t *testing.T) {
+	parsed, err := Parse([]byte{0x70, 0x08, 0xC1, 0x04, 0x5A, 0x02, 0x12, 0x34, 0x90, 0x00})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	received := parsed.DecodeHeuristic(DefaultHeuristicOptions)
+
+	speculative := received[0].FirstChild(NewOneByteTag(0xC1)).Speculative()
+	expected := BerTLVs{{Tag: NewOneByteTag(0x5A), Value: []byte{0x12, 0x34}}}
+
+	if !cmp.Equal(speculative, expected, cmpParsed) {
+		t.Errorf("Expected: '%v', got: '%v'", expected, speculative)
+	}
+
+	span, _ := speculative[0].Span()
+	if span.Offset != 4 {
+		t.Errorf("Expected: offset 4, got: %d", span.Offset)
+	}
+}
+
+func TestDump_Heuristic(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	err := Dump(buf, []byte{0xC1, 0x06, 0x90, 0x01, 0xFF, 0x91, 0x01, 0xEE}, DumpOptions{Heuristic: &DefaultHeuristicOptions})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	expected := strings.Join([]string{
+		"    0: d=0  hl=2 l=    6 prim: C1 06",
+		"    2: d=1  hl=2 l=    1 prim?   90 01",
+		"                                   FF                                               |.|",
+		"    5: d=1  hl=2 l=    1 prim?   91 01",
+		"                                   EE                                               |.|",
+	}, "\n") + "\n"
+
+	if received := buf.String(); received != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, received)
+	}
+}
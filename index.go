@@ -0,0 +1,135 @@
+package bertlv
+
+import "fmt"
+
+// TagID is a comparable representation of a BerTag that can be used as map key and compared with ==.
+// The tag bytes are stored big-endian, e.g. the tag '5F 20' has the TagID 0x5F20.
+type TagID uint32
+
+// ID returns the TagID of the BerTag. The TagID is unique for tags of one to four bytes, which covers all tags
+// supported by Parse and all tag numbers up to 2097151 created with NewTag. For longer tags only the first four
+// bytes are used and the empty tag has the same TagID as the tag '00', so these tags can not be told apart by their
+// TagID, see NewIndex.
+func (t BerTag) ID() TagID {
+	var id TagID
+
+	for i, b := range t {
+		if i == 4 {
+			break
+		}
+
+		id = id<<8 | TagID(b)
+	}
+
+	return id
+}
+
+// hasUniqueID returns true if no other BerTag has the same TagID.
+func (t BerTag) hasUniqueID() bool {
+	return len(t) > 0 && len(t) <= 4
+}
+
+// Tag returns the BerTag of the TagID.
+func (id TagID) Tag() BerTag {
+	switch {
+	case id <= 0xFF:
+		return NewOneByteTag(byte(id))
+	case id <= 0xFFFF:
+		return NewTwoByteTag(byte(id>>8), byte(id))
+	case id <= 0xFFFFFF:
+		return NewThreeByteTag(byte(id>>16), byte(id>>8), byte(id))
+	default:
+		return BerTag{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	}
+}
+
+// String returns the tag bytes hex encoded (upper-case), e.g. "5F20".
+func (id TagID) String() string {
+	return fmt.Sprintf("%X", []byte(id.Tag()))
+}
+
+// Index provides lookup of the BerTLV objects of BerTLVs by TagID in constant time.
+// Use NewIndex to create an Index. The Index is not updated if the BerTLVs are modified.
+type Index struct {
+	firstOrder map[TagID][]BerTLV
+	all        map[TagID][]BerTLV
+}
+
+// NewIndex returns a new Index of the given BerTLVs including all child objects.
+// Pseudo-objects for malformed input (see BerTLV.Unparsed) are not indexed. Objects with an empty tag or a tag of
+// more than four bytes are not indexed either, since their TagID is not unique (see BerTag.ID) and they could be
+// confused with other objects. Use BerTLVs.FindAllWithTag for them.
+func NewIndex(t BerTLVs) *Index {
+	idx := &Index{
+		firstOrder: make(map[TagID][]BerTLV),
+		all:        make(map[TagID][]BerTLV),
+	}
+
+	for _, tlv := range t {
+		if tlv.unparsed == nil && tlv.Tag.hasUniqueID() {
+			id := tlv.Tag.ID()
+			idx.firstOrder[id] = append(idx.firstOrder[id], tlv)
+		}
+	}
+
+	// walk the tree in the order of the encoding
+	pending := make([]BerTLV, 0, len(t))
+	for i := len(t) - 1; i >= 0; i-- {
+		pending = append(pending, t[i])
+	}
+
+	for len(pending) > 0 {
+		tlv := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if tlv.unparsed == nil && tlv.Tag.hasUniqueID() {
+			id := tlv.Tag.ID()
+			idx.all[id] = append(idx.all[id], tlv)
+		}
+
+		for i := len(tlv.children) - 1; i >= 0; i-- {
+			pending = append(pending, tlv.children[i])
+		}
+	}
+
+	return idx
+}
+
+// FindAllWithTag returns all first order BerTLV whose tag matches the given TagID in the order they are found.
+//
+// Returns nil if no matching BerTLV is found.
+func (idx *Index) FindAllWithTag(tag TagID) []BerTLV {
+	return idx.firstOrder[tag]
+}
+
+// FindFirstWithTag returns the first found first order BerTLV whose tag matches the given TagID.
+//
+// Returns nil if no matching BerTLV is found.
+func (idx *Index) FindFirstWithTag(tag TagID) *BerTLV {
+	return first(idx.firstOrder[tag])
+}
+
+// FindAllWithTagRecursive returns all BerTLV at any depth whose tag matches the given TagID in the order
+// they are encoded, i.e. constructed objects precede their children.
+//
+// Returns nil if no matching BerTLV is found.
+func (idx *Index) FindAllWithTagRecursive(tag TagID) []BerTLV {
+	return idx.all[tag]
+}
+
+// FindFirstWithTagRecursive returns the first encoded BerTLV at any depth whose tag matches the given TagID.
+//
+// Returns nil if no matching BerTLV is found.
+func (idx *Index) FindFirstWithTagRecursive(tag TagID) *BerTLV {
+	return first(idx.all[tag])
+}
+
+func first(tlvs []BerTLV) *BerTLV {
+	if len(tlvs) == 0 {
+		return nil
+	}
+
+	tlv := tlvs[0]
+
+	return &tlv
+}
@@ -0,0 +1,197 @@
+package bertlv
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBerTag_ID(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      BerTag
+		expected TagID
+	}{
+		{
+			name:     "one byte",
+			tag:      NewOneByteTag(0x5A),
+			expected: 0x5A,
+		},
+		{
+			name:     "two byte",
+			tag:      NewTwoByteTag(0x5F, 0x20),
+			expected: 0x5F20,
+		},
+		{
+			name:     "three byte",
+			tag:      NewThreeByteTag(0xDF, 0x81, 0x01),
+			expected: 0xDF8101,
+		},
+		{
+			name:     "four byte",
+			tag:      BerTag{0x1F, 0x81, 0x80, 0x00},
+			expected: 0x1F818000,
+		},
+		{
+			name:     "truncate to four byte",
+			tag:      BerTag{0x1F, 0x81, 0x80, 0x80, 0x01},
+			expected: 0x1F818080,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received := tc.tag.ID()
+			if received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+
+			if len(tc.tag) <= 4 && !cmp.Equal(received.Tag(), tc.tag) {
+				t.Errorf("Expected: '%X', got: '%X'", []byte(tc.tag), []byte(received.Tag()))
+			}
+		})
+	}
+}
+
+func TestTagID_String(t *testing.T) {
+	tests := []struct {
+		id       TagID
+		expected string
+	}{
+		{id: 0x00, expected: "00"},
+		{id: 0x5A, expected: "5A"},
+		{id: 0x5F20, expected: "5F20"},
+		{id: 0xDF8101, expected: "DF8101"},
+		{id: 0x1F818000, expected: "1F818000"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expected, func(t *testing.T) {
+			if received := tc.id.String(); received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	input := []byte{
+		0x70, 0x0A, 0x5A, 0x01, 0x01, 0xA5, 0x05, 0x5A, 0x01, 0x02, 0x90, 0x00,
+		0x5A, 0x01, 0x03,
+		0x90, 0x01, 0x04,
+	}
+
+	tlvs, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	idx := NewIndex(tlvs)
+
+	values := func(tlvs []BerTLV) [][]byte {
+		var result [][]byte
+
+		for _, tlv := range tlvs {
+			result = append(result, tlv.Value)
+		}
+
+		return result
+	}
+
+	tests := []struct {
+		name                   string
+		tag                    TagID
+		expectedFirstOrder     [][]byte
+		expectedRecursive      [][]byte
+		expectFirstOrderResult bool
+	}{
+		{
+			name:                   "first order and nested",
+			tag:                    0x5A,
+			expectedFirstOrder:     [][]byte{{0x03}},
+			expectedRecursive:      [][]byte{{0x01}, {0x02}, {0x03}},
+			expectFirstOrderResult: true,
+		},
+		{
+			name:                   "empty and primitive",
+			tag:                    0x90,
+			expectedFirstOrder:     [][]byte{{0x04}},
+			expectedRecursive:      [][]byte{nil, {0x04}},
+			expectFirstOrderResult: true,
+		},
+		{
+			name:                   "nested only",
+			tag:                    0xA5,
+			expectedFirstOrder:     nil,
+			expectedRecursive:      [][]byte{{0x5A, 0x01, 0x02, 0x90, 0x00}},
+			expectFirstOrderResult: false,
+		},
+		{
+			name:                   "not found",
+			tag:                    0x5F20,
+			expectedFirstOrder:     nil,
+			expectedRecursive:      nil,
+			expectFirstOrderResult: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if received := values(idx.FindAllWithTag(tc.tag)); !cmp.Equal(received, tc.expectedFirstOrder) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expectedFirstOrder, received)
+			}
+
+			if received := values(idx.FindAllWithTagRecursive(tc.tag)); !cmp.Equal(received, tc.expectedRecursive) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expectedRecursive, received)
+			}
+
+			firstOrder := idx.FindFirstWithTag(tc.tag)
+			if (firstOrder != nil) != tc.expectFirstOrderResult {
+				t.Errorf("Expected: result %v, got: '%v'", tc.expectFirstOrderResult, firstOrder)
+			}
+
+			if firstOrder != nil && !cmp.Equal(firstOrder.Value, tc.expectedFirstOrder[0]) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expectedFirstOrder[0], firstOrder.Value)
+			}
+
+			recursive := idx.FindFirstWithTagRecursive(tc.tag)
+			if (recursive != nil) != (tc.expectedRecursive != nil) {
+				t.Errorf("Expected: result %v, got: '%v'", tc.expectedRecursive != nil, recursive)
+			}
+
+			if recursive != nil && !cmp.Equal(recursive.Value, tc.expectedRecursive[0]) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expectedRecursive[0], recursive.Value)
+			}
+		})
+	}
+}
+
+func TestNewIndex_AmbiguousTags(t *testing.T) {
+	tlvs := BerTLVs{
+		{Tag: BerTag{}, Value: []byte{0x01}},
+		{Tag: NewOneByteTag(0x00), Value: []byte{0x02}},
+		{Tag: BerTag{0x1F, 0x81, 0x80, 0x80, 0x01}, Value: []byte{0x03}},
+		{Tag: BerTag{0x1F, 0x81, 0x80, 0x80, 0x02}, Value: []byte{0x04}},
+	}
+
+	// the TagIDs collide
+	if tlvs[0].Tag.ID() != tlvs[1].Tag.ID() || tlvs[2].Tag.ID() != tlvs[3].Tag.ID() {
+		t.Fatalf("Expected: colliding TagIDs, got: %v, %v, %v, %v", tlvs[0].Tag.ID(), tlvs[1].Tag.ID(), tlvs[2].Tag.ID(), tlvs[3].Tag.ID())
+	}
+
+	idx := NewIndex(tlvs)
+
+	// only the object with tag '00' is indexed
+	received := idx.FindAllWithTagRecursive(0x00)
+	if len(received) != 1 || !cmp.Equal(received[0].Value, []byte{0x02}) {
+		t.Errorf("Expected: object with tag '00', got: '%v'", received)
+	}
+
+	if received := idx.FindAllWithTag(tlvs[2].Tag.ID()); received != nil {
+		t.Errorf("Expected: nil, got: '%v'", received)
+	}
+
+	if received := tlvs.FindAllWithTag(tlvs[3].Tag); len(received) != 1 || !cmp.Equal(received[0].Value, []byte{0x04}) {
+		t.Errorf("Expected: object with tag '1F81808002', got: '%v'", received)
+	}
+}
@@ -0,0 +1,437 @@
+package bertlv
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ParseOptions configures ParseWithOptions.
+// Limits that are zero or negative are not applied, so the zero value of ParseOptions parses like Parse.
+type ParseOptions struct {
+	// MaxDepth is the maximum nesting depth of BER-TLV objects. Top level objects have depth 1,
+	// their children depth 2 and so on.
+	MaxDepth int
+	// MaxNodes is the maximum number of BER-TLV objects, including all child objects.
+	MaxNodes int
+	// MaxValueLength is the maximum length of the value of a single BER-TLV object.
+	MaxValueLength int
+	// MaxInputLength is the maximum length of the input.
+	MaxInputLength int
+	// Resynchronize is only used by ParseLenient. If set, parsing continues at the next plausible BER-TLV object
+	// after malformed bytes instead of skipping the remaining value of the enclosing object or input.
+	Resynchronize bool
+	// Padding contains filler bytes like 00 and FF that may occur before, between and after BER-TLV objects,
+	// as permitted by ISO/IEC 7816-4 and EMV. Padding is skipped on the top level and inside constructed objects.
+	// Since skipped padding is not part of the result, it is not reproduced by BerTLVs.Encode.
+	Padding []byte
+	// OnPadding is optionally called for each run of skipped padding bytes with its offset in the input and its length.
+	OnPadding func(offset int, length int)
+	// Form optionally overrides whether the value of an object with the given tag is parsed as nested objects,
+	// e.g. for applets that put BER-TLV objects in the value of primitive tags or set b6 on tags whose value is not
+	// BER-TLV encoded. It is applied to objects at any depth.
+	Form func(tag BerTag) Form
+	// CopyInput copies the input into one new backing array before parsing. By default, the Value of every parsed
+	// object is a slice of the input, so reusing or modifying the input buffer (e.g. a pooled APDU buffer) changes
+	// previously parsed objects. With CopyInput set, the result does not refer to the input and is safe to keep
+	// after the buffer is reused. Use BerTLV.Clone to copy objects that have already been parsed.
+	CopyInput bool
+	// CopyInto optionally provides the buffer the input is copied into before parsing like with CopyInput.
+	// The caller keeps control of all memory the result refers to, including padding and bytes between objects,
+	// and can wipe it after use, e.g. with BerTLVs.Wipe followed by zeroing the buffer. Parsing fails if the buffer
+	// is shorter than the input.
+	CopyInto []byte
+}
+
+// Limit identifies a limit of ParseOptions.
+type Limit int
+
+const (
+	LimitDepth       Limit = iota // ParseOptions.MaxDepth
+	LimitNodes       Limit = iota // ParseOptions.MaxNodes
+	LimitValueLength Limit = iota // ParseOptions.MaxValueLength
+	LimitInputLength Limit = iota // ParseOptions.MaxInputLength
+)
+
+// String returns the name of the field of ParseOptions that sets the Limit.
+func (l Limit) String() string {
+	switch l {
+	case LimitDepth:
+		return "MaxDepth"
+	case LimitNodes:
+		return "MaxNodes"
+	case LimitValueLength:
+		return "MaxValueLength"
+	case LimitInputLength:
+		return "MaxInputLength"
+	default:
+		return fmt.Sprintf("Limit(%d)", int(l))
+	}
+}
+
+// LimitError is returned when parsing exceeds one of the limits set in ParseOptions.
+// It may be wrapped, use errors.As to retrieve it.
+type LimitError struct {
+	Limit  Limit // Limit that was exceeded.
+	Max    int   // Configured maximum.
+	Offset int   // Offset in the input of the BER-TLV object that exceeded the limit.
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("limit %s of %d exceeded at offset %d", e.Limit, e.Max, e.Offset)
+}
+
+// ParseWithOptions parses BER-TLV encoded bytes like Parse and applies the given ParseOptions.
+//
+// If a limit is exceeded, an error wrapping a *LimitError is returned.
+func ParseWithOptions(b []byte, opts ParseOptions) (BerTLVs, error) {
+	if len(b) == 0 {
+		return nil, errors.Errorf("%s: TLV has length 0", packageTag)
+	}
+
+	if opts.MaxInputLength > 0 && len(b) > opts.MaxInputLength {
+		return BerTLVs{}, errors.Wrap(&LimitError{Limit: LimitInputLength, Max: opts.MaxInputLength}, packageTag)
+	}
+
+	b, err := opts.input(b)
+	if err != nil {
+		return BerTLVs{}, errors.Wrap(err, packageTag)
+	}
+
+	p := parser{opts: opts}
+
+	result, _, err := p.parse(b, 0, false)
+	if err != nil {
+		return BerTLVs{}, errors.Wrap(err, packageTag)
+	}
+
+	return result, nil
+}
+
+// ParseFirstWithOptions parses the first BER-TLV object of b like ParseFirst and applies the given ParseOptions.
+// Padding preceding the object is consumed, padding following it is part of the remaining bytes.
+// ParseOptions.MaxInputLength is not applied, since the remaining bytes are not parsed.
+func ParseFirstWithOptions(b []byte, opts ParseOptions) (BerTLV, int, []byte, error) {
+	if len(b) == 0 {
+		return BerTLV{}, 0, nil, errors.Errorf("%s: TLV has length 0", packageTag)
+	}
+
+	b, err := opts.input(b)
+	if err != nil {
+		return BerTLV{}, 0, nil, errors.Wrap(err, packageTag)
+	}
+
+	p := parser{opts: opts}
+
+	result, lenParsed, err := p.parse(b, 0, true)
+	if err != nil {
+		return BerTLV{}, 0, nil, errors.Wrap(err, packageTag)
+	}
+
+	if len(result) == 0 {
+		return BerTLV{}, 0, nil, errors.Errorf("%s: only padding found", packageTag)
+	}
+
+	return result[0], lenParsed, b[lenParsed:], nil
+}
+
+// input returns the bytes to parse, which are a copy of b if CopyInput or CopyInto is set.
+func (opts ParseOptions) input(b []byte) ([]byte, error) {
+	switch {
+	case opts.CopyInto != nil:
+		if len(opts.CopyInto) < len(b) {
+			return b, errors.Errorf("buffer of length %d is too short for input of length %d", len(opts.CopyInto), len(b))
+		}
+
+		return opts.CopyInto[:copy(opts.CopyInto, b)], nil
+	case opts.CopyInput:
+		return cloneBytes(b), nil
+	}
+
+	return b, nil
+}
+
+// Problem describes malformed input found by ParseLenient.
+type Problem struct {
+	Offset int   // Offset of the malformed bytes in the input.
+	Length int   // Number of bytes that were not parsed because of the problem.
+	Err    error // Reason why the bytes could not be parsed.
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("%d unparsed bytes at offset %d: %s", p.Length, p.Offset, p.Err)
+}
+
+// ParseLenient parses BER-TLV encoded bytes on a best-effort basis. Instead of failing on the first malformed byte,
+// malformed regions are returned as pseudo-objects that can be recognized with BerTLV.Unparsed,
+// so all correctly encoded objects before and after them are kept. Each malformed region is listed as Problem.
+//
+// The bytes of a malformed region are skipped up to the end of the value of the enclosing constructed object or the
+// end of the input, unless ParseOptions.Resynchronize is set. Then parsing continues at the next position where a
+// correctly encoded object starts. If a limit is exceeded, the remaining bytes are not parsed.
+//
+// Encoding the result with PreserveEncoding reproduces the input byte-exact.
+func ParseLenient(b []byte, opts ParseOptions) (BerTLVs, []Problem) {
+	if len(b) == 0 {
+		return nil, []Problem{{Err: errors.New("TLV has length 0")}}
+	}
+
+	b, err := opts.input(b)
+	if err != nil {
+		return nil, []Problem{{Length: len(b), Err: err}}
+	}
+
+	if opts.MaxInputLength > 0 && len(b) > opts.MaxInputLength {
+		err := &LimitError{Limit: LimitInputLength, Max: opts.MaxInputLength}
+
+		return BerTLVs{{Value: b, unparsed: err}}, []Problem{{Length: len(b), Err: err}}
+	}
+
+	p := parser{opts: opts, lenient: true}
+
+	// parsing never fails in lenient mode
+	result, _, _ := p.parse(b, 0, false)
+
+	return result, p.problems
+}
+
+// parser parses BER-TLV objects without recursion, so the nesting depth of the input is only bounded by memory
+// and ParseOptions.MaxDepth.
+type parser struct {
+	opts     ParseOptions
+	nodes    int       // Number of parsed objects.
+	lenient  bool      // Record problems and continue instead of failing on malformed input.
+	problems []Problem // Problems found in lenient mode.
+	stopErr  error     // Limit error that stopped parsing in lenient mode.
+}
+
+// frame is a constructed BER-TLV object whose children are being parsed.
+type frame struct {
+	tlv BerTLV
+	end int // Index in the parsed bytes of the first byte following the value.
+}
+
+// parse parses the BER-TLV objects contained in b. offset is the position of b in the input.
+// If first is true, parse returns after the first top level object.
+// It returns the parsed objects and the number of bytes parsed.
+func (p *parser) parse(b []byte, offset int, first bool) ([]BerTLV, int, error) {
+	var (
+		result []BerTLV
+		stack  []frame
+	)
+
+	// add adds a completely parsed object to the innermost constructed object or to the result
+	add := func(tlv BerTLV) {
+		if len(stack) == 0 {
+			result = append(result, tlv)
+
+			return
+		}
+
+		parent := &stack[len(stack)-1].tlv
+		parent.children = append(parent.children, tlv)
+	}
+
+	pos := 0
+
+	for {
+		// complete constructed objects whose value has been parsed entirely
+		for len(stack) > 0 && pos == stack[len(stack)-1].end {
+			done := stack[len(stack)-1].tlv
+			stack = stack[:len(stack)-1]
+
+			add(done)
+		}
+
+		if pos == len(b) || (first && len(stack) == 0 && len(result) == 1) {
+			return result, pos, nil
+		}
+
+		end := len(b)
+		if len(stack) > 0 {
+			end = stack[len(stack)-1].end
+		}
+
+		if skip := p.skipPadding(b[pos:end]); skip > 0 {
+			if p.opts.OnPadding != nil {
+				p.opts.OnPadding(offset+pos, skip)
+			}
+
+			pos += skip
+
+			continue
+		}
+
+		if p.stopErr != nil {
+			add(BerTLV{Value: b[pos:end], offset: offset + pos, unparsed: p.stopErr})
+			p.problems = append(p.problems, Problem{Offset: offset + pos, Length: end - pos, Err: p.stopErr})
+			pos = end
+
+			continue
+		}
+
+		tlv, hLen, err := p.parseObject(b[pos:end], offset+pos, len(stack)+1)
+		if err != nil && p.lenient {
+			skip := p.skipMalformed(b[pos:end], offset+pos, len(stack)+1, err)
+			add(BerTLV{Value: b[pos : pos+skip], offset: offset + pos, unparsed: err})
+			pos += skip
+
+			continue
+		}
+
+		if err != nil {
+			for i := len(stack) - 1; i >= 0; i-- {
+				err = errors.Wrap(err, fmt.Sprintf("tag %02X: invalid child object at offset %d", []byte(stack[i].tlv.Tag), offset+pos))
+				pos = stack[i].tlv.offset - offset
+			}
+
+			return nil, 0, errors.Wrap(err, fmt.Sprintf("invalid TLV starting at index %d", offset+pos))
+		}
+
+		if isConstructed(tlv.Tag, p.opts.Form) && len(tlv.Value) > 0 {
+			tlv.children = make([]BerTLV, 0, len(tlv.Value)/2)
+			stack = append(stack, frame{tlv: tlv, end: pos + hLen + len(tlv.Value)})
+			pos += hLen
+
+			continue
+		}
+
+		add(tlv)
+
+		pos += hLen + len(tlv.Value)
+	}
+}
+
+// skipPadding returns the number of padding bytes at the start of b.
+func (p *parser) skipPadding(b []byte) int {
+	if len(p.opts.Padding) == 0 {
+		return 0
+	}
+
+	for i, c := range b {
+		if bytes.IndexByte(p.opts.Padding, c) < 0 {
+			return i
+		}
+	}
+
+	return len(b)
+}
+
+// skipMalformed records a problem for the malformed bytes at the start of b and returns the number of bytes that
+// are marked as unparsed. If resynchronization is enabled, parsing continues at the next plausible BER-TLV object,
+// otherwise all bytes are skipped. Exceeding a limit stops parsing.
+func (p *parser) skipMalformed(b []byte, offset int, depth int, err error) int {
+	skip := len(b)
+
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		p.stopErr = err
+	} else if p.opts.Resynchronize {
+		for i := 1; i < len(b); i++ {
+			if plausible(b[i:], depth, p.opts) {
+				skip = i
+
+				break
+			}
+		}
+	}
+
+	p.problems = append(p.problems, Problem{Offset: offset, Length: skip, Err: err})
+
+	return skip
+}
+
+// plausibleNodes is the number of objects in the value of a constructed object that plausible checks.
+// Checking a fixed number of headers instead of parsing the whole value keeps resynchronization linear in the
+// length of the input.
+const plausibleNodes = 16
+
+// plausible returns true if b starts with a correctly encoded BER-TLV object. For constructed objects, the headers
+// of the first plausibleNodes objects in the value, including nested ones, must be correctly encoded and within
+// the bounds of their enclosing objects.
+func plausible(b []byte, depth int, opts ParseOptions) bool {
+	tag, length, hLen, err := parseHeader(b)
+	if err != nil || tag.CheckEncoding() != nil {
+		return false
+	}
+
+	if !isConstructed(tag, opts.Form) || length == 0 {
+		return true
+	}
+
+	p := parser{opts: opts}
+	ends := []int{hLen + length} // ends of the values of the enclosing constructed objects
+	pos := hLen
+
+	for n := 0; n < plausibleNodes; {
+		for len(ends) > 0 && pos == ends[len(ends)-1] {
+			ends = ends[:len(ends)-1]
+		}
+
+		if len(ends) == 0 {
+			return true
+		}
+
+		end := ends[len(ends)-1]
+
+		if skip := p.skipPadding(b[pos:end]); skip > 0 {
+			pos += skip
+
+			continue
+		}
+
+		if opts.MaxDepth > 0 && depth+len(ends) > opts.MaxDepth {
+			return false
+		}
+
+		tag, length, hLen, err := parseHeader(b[pos:end])
+		if err != nil || (opts.MaxValueLength > 0 && length > opts.MaxValueLength) {
+			return false
+		}
+
+		n++
+
+		if isConstructed(tag, opts.Form) && length > 0 {
+			ends = append(ends, pos+hLen+length)
+			pos += hLen
+
+			continue
+		}
+
+		pos += hLen + length
+	}
+
+	return true
+}
+
+// parseObject parses tag and length of the BER-TLV object at the start of b and checks the limits for an object
+// at the given depth. The children of constructed objects are not parsed.
+// It returns the object and the length of its header.
+func (p *parser) parseObject(b []byte, offset int, depth int) (BerTLV, int, error) {
+	if p.opts.MaxDepth > 0 && depth > p.opts.MaxDepth {
+		return BerTLV{}, 0, &LimitError{Limit: LimitDepth, Max: p.opts.MaxDepth, Offset: offset}
+	}
+
+	p.nodes++
+	if p.opts.MaxNodes > 0 && p.nodes > p.opts.MaxNodes {
+		return BerTLV{}, 0, &LimitError{Limit: LimitNodes, Max: p.opts.MaxNodes, Offset: offset}
+	}
+
+	tag, length, hLen, err := parseHeader(b)
+	if err != nil {
+		return BerTLV{}, 0, err
+	}
+
+	if p.opts.MaxValueLength > 0 && length > p.opts.MaxValueLength {
+		return BerTLV{}, 0, &LimitError{Limit: LimitValueLength, Max: p.opts.MaxValueLength, Offset: offset}
+	}
+
+	result := BerTLV{Tag: tag, header: b[:hLen:hLen], offset: offset}
+
+	if length > 0 {
+		result.Value = b[hLen : hLen+length]
+	}
+
+	return result, hLen, nil
+}
@@ -0,0 +1,538 @@
+package bertlv
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// nested returns depth nested constructed objects with tag 0x30, the innermost object is empty.
+func nested(depth int) []byte {
+	b := []byte{0x30, 0x00}
+
+	for i := 1; i < depth; i++ {
+		b = append(append([]byte{0x30}, buildLen(len(b))...), b...)
+	}
+
+	return b
+}
+
+func TestParseWithOptions(t *testing.T) {
+	input := []byte{0x70, 0x08, 0xA1, 0x03, 0x90, 0x01, 0xFF, 0x91, 0x01, 0xEE, 0x92, 0x00}
+
+	tests := []struct {
+		name          string
+		inputBytes    []byte
+		opts          ParseOptions
+		expectedLen   int
+		expectedLimit *LimitError
+	}{
+		{
+			name:        "no limits",
+			inputBytes:  input,
+			opts:        ParseOptions{},
+			expectedLen: 2,
+		},
+		{
+			name:        "within limits",
+			inputBytes:  input,
+			opts:        ParseOptions{MaxDepth: 3, MaxNodes: 5, MaxValueLength: 8, MaxInputLength: 12},
+			expectedLen: 2,
+		},
+		{
+			name:        "deep nesting without limit",
+			inputBytes:  nested(10000),
+			opts:        ParseOptions{},
+			expectedLen: 1,
+		},
+		{
+			name:          "Error: depth",
+			inputBytes:    input,
+			opts:          ParseOptions{MaxDepth: 2},
+			expectedLimit: &LimitError{Limit: LimitDepth, Max: 2, Offset: 4},
+		},
+		{
+			name:          "Error: deep nesting",
+			inputBytes:    nested(10000),
+			opts:          ParseOptions{MaxDepth: 64},
+			expectedLimit: &LimitError{Limit: LimitDepth, Max: 64, Offset: len(nested(10000)) - len(nested(10000-64))},
+		},
+		{
+			name:          "Error: nodes",
+			inputBytes:    input,
+			opts:          ParseOptions{MaxNodes: 4},
+			expectedLimit: &LimitError{Limit: LimitNodes, Max: 4, Offset: 10},
+		},
+		{
+			name:          "Error: value length",
+			inputBytes:    input,
+			opts:          ParseOptions{MaxValueLength: 3},
+			expectedLimit: &LimitError{Limit: LimitValueLength, Max: 3, Offset: 0},
+		},
+		{
+			name:          "Error: input length",
+			inputBytes:    input,
+			opts:          ParseOptions{MaxInputLength: 11},
+			expectedLimit: &LimitError{Limit: LimitInputLength, Max: 11, Offset: 0},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := ParseWithOptions(tc.inputBytes, tc.opts)
+
+			if tc.expectedLimit == nil {
+				if err != nil {
+					t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+				}
+
+				if len(received) != tc.expectedLen {
+					t.Errorf("Expected: %d BerTLVs, got: %d", tc.expectedLen, len(received))
+				}
+
+				return
+			}
+
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("Expected: LimitError, got: %v", err)
+			}
+
+			if !cmp.Equal(limitErr, tc.expectedLimit) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expectedLimit, limitErr)
+			}
+		})
+	}
+}
+
+func TestLimit_String(t *testing.T) {
+	tests := []struct {
+		limit    Limit
+		expected string
+	}{
+		{limit: LimitDepth, expected: "MaxDepth"},
+		{limit: LimitNodes, expected: "MaxNodes"},
+		{limit: LimitValueLength, expected: "MaxValueLength"},
+		{limit: LimitInputLength, expected: "MaxInputLength"},
+		{limit: Limit(10), expected: "Limit(10)"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expected, func(t *testing.T) {
+			if received := tc.limit.String(); received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	malformed := errors.New("malformed")
+
+	// compare unparsed pseudo-objects only by the presence of a reason
+	cmpLenient := cmp.Options{
+		cmpParsed,
+		cmp.Comparer(func(a, b error) bool { return (a == nil) == (b == nil) }),
+	}
+
+	// constructed object whose value is malformed after more than plausibleNodes children
+	budget := []byte{0x01, 0x70, 2*plausibleNodes + 2}
+	budgetChildren := make([]BerTLV, 0, plausibleNodes+1)
+
+	for i := 0; i < plausibleNodes; i++ {
+		budget = append(budget, 0x90, 0x00)
+		budgetChildren = append(budgetChildren, BerTLV{Tag: NewOneByteTag(0x90)})
+	}
+
+	budget = append(budget, 0x91, 0x05)
+	budgetChildren = append(budgetChildren, BerTLV{Value: []byte{0x91, 0x05}, unparsed: malformed})
+
+	tests := []struct {
+		name             string
+		inputBytes       []byte
+		opts             ParseOptions
+		expected         BerTLVs
+		expectedProblems []Problem
+	}{
+		{
+			name:       "valid",
+			inputBytes: []byte{0x90, 0x01, 0xFF},
+			expected: BerTLVs{
+				{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+			},
+			expectedProblems: nil,
+		},
+		{
+			name:       "malformed rest of input",
+			inputBytes: []byte{0x90, 0x01, 0xFF, 0x91, 0x05, 0x01},
+			expected: BerTLVs{
+				{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+				{Value: []byte{0x91, 0x05, 0x01}, unparsed: malformed},
+			},
+			expectedProblems: []Problem{{Offset: 3, Length: 3}},
+		},
+		{
+			name:       "malformed child, continue after constructed object",
+			inputBytes: []byte{0x70, 0x05, 0x90, 0x01, 0xFF, 0x91, 0x05, 0x92, 0x01, 0xEE},
+			expected: BerTLVs{
+				{
+					Tag:   NewOneByteTag(0x70),
+					Value: []byte{0x90, 0x01, 0xFF, 0x91, 0x05},
+					children: []BerTLV{
+						{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+						{Value: []byte{0x91, 0x05}, unparsed: malformed},
+					},
+				},
+				{Tag: NewOneByteTag(0x92), Value: []byte{0xEE}},
+			},
+			expectedProblems: []Problem{{Offset: 5, Length: 2}},
+		},
+		{
+			name:       "without resynchronization",
+			inputBytes: []byte{0x90, 0x01, 0xFF, 0x1F, 0x92, 0x01, 0xEE},
+			expected: BerTLVs{
+				{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+				{Value: []byte{0x1F, 0x92, 0x01, 0xEE}, unparsed: malformed},
+			},
+			expectedProblems: []Problem{{Offset: 3, Length: 4}},
+		},
+		{
+			name:       "with resynchronization",
+			inputBytes: []byte{0x90, 0x01, 0xFF, 0x1F, 0x92, 0x01, 0xEE},
+			opts:       ParseOptions{Resynchronize: true},
+			expected: BerTLVs{
+				{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+				{Value: []byte{0x1F}, unparsed: malformed},
+				{Tag: NewOneByteTag(0x92), Value: []byte{0xEE}},
+			},
+			expectedProblems: []Problem{{Offset: 3, Length: 1}},
+		},
+		{
+			name:       "resynchronization skips implausible constructed objects",
+			inputBytes: []byte{0x01, 0x70, 0x02, 0x91, 0x05, 0x92, 0x00},
+			opts:       ParseOptions{Resynchronize: true},
+			expected: BerTLVs{
+				{Value: []byte{0x01, 0x70, 0x02, 0x91, 0x05}, unparsed: malformed},
+				{Tag: NewOneByteTag(0x92)},
+			},
+			expectedProblems: []Problem{{Offset: 0, Length: 5}},
+		},
+		{
+			name:       "resynchronization checks only the first objects of constructed values",
+			inputBytes: budget,
+			opts:       ParseOptions{Resynchronize: true},
+			expected: BerTLVs{
+				{Value: []byte{0x01}, unparsed: malformed},
+				{Tag: NewOneByteTag(0x70), Value: budget[3:], children: budgetChildren},
+			},
+			expectedProblems: []Problem{{Offset: 0, Length: 1}, {Offset: len(budget) - 2, Length: 2}},
+		},
+		{
+			name:       "limit stops parsing",
+			inputBytes: []byte{0x90, 0x01, 0xFF, 0x70, 0x06, 0x91, 0x01, 0xEE, 0x92, 0x01, 0xDD, 0x93, 0x00},
+			opts:       ParseOptions{MaxNodes: 3, Resynchronize: true},
+			expected: BerTLVs{
+				{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+				{
+					Tag:   NewOneByteTag(0x70),
+					Value: []byte{0x91, 0x01, 0xEE, 0x92, 0x01, 0xDD},
+					children: []BerTLV{
+						{Tag: NewOneByteTag(0x91), Value: []byte{0xEE}},
+						{Value: []byte{0x92, 0x01, 0xDD}, unparsed: malformed},
+					},
+				},
+				{Value: []byte{0x93, 0x00}, unparsed: malformed},
+			},
+			expectedProblems: []Problem{{Offset: 8, Length: 3}, {Offset: 11, Length: 2}},
+		},
+		{
+			name:       "input length limit",
+			inputBytes: []byte{0x90, 0x01, 0xFF},
+			opts:       ParseOptions{MaxInputLength: 2},
+			expected: BerTLVs{
+				{Value: []byte{0x90, 0x01, 0xFF}, unparsed: malformed},
+			},
+			expectedProblems: []Problem{{Offset: 0, Length: 3}},
+		},
+		{
+			name:             "empty",
+			inputBytes:       nil,
+			expected:         nil,
+			expectedProblems: []Problem{{}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, problems := ParseLenient(tc.inputBytes, tc.opts)
+
+			if !cmp.Equal(received, tc.expected, cmpLenient) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+
+			if !cmp.Equal(problems, tc.expectedProblems, cmpopts.IgnoreFields(Problem{}, "Err")) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expectedProblems, problems)
+			}
+
+			for _, problem := range problems {
+				if problem.Err == nil {
+					t.Errorf("Expected: reason for problem, got: nil")
+				}
+			}
+
+			if encoded := received.Encode(PreserveEncoding); !bytes.Equal(encoded, tc.inputBytes) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.inputBytes, encoded)
+			}
+		})
+	}
+}
+
+func TestParseWithOptions_Padding(t *testing.T) {
+	type padding struct {
+		offset int
+		length int
+	}
+
+	tests := []struct {
+		name            string
+		inputBytes      []byte
+		padding         []byte
+		expected        BerTLVs
+		expectedPadding []padding
+		expectError     bool
+	}{
+		{
+			name:       "padding before, between and after objects",
+			inputBytes: []byte{0x00, 0x00, 0x90, 0x01, 0xFF, 0xFF, 0x91, 0x00, 0x00, 0xFF},
+			padding:    []byte{0x00, 0xFF},
+			expected: BerTLVs{
+				{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+				{Tag: NewOneByteTag(0x91)},
+			},
+			expectedPadding: []padding{{offset: 0, length: 2}, {offset: 5, length: 1}, {offset: 8, length: 2}},
+		},
+		{
+			name:       "padding inside constructed object",
+			inputBytes: []byte{0x70, 0x06, 0x00, 0x90, 0x01, 0xFF, 0x00, 0x00, 0x91, 0x00},
+			padding:    []byte{0x00},
+			expected: BerTLVs{
+				{
+					Tag:   NewOneByteTag(0x70),
+					Value: []byte{0x00, 0x90, 0x01, 0xFF, 0x00, 0x00},
+					children: []BerTLV{
+						{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+					},
+				},
+				{Tag: NewOneByteTag(0x91)},
+			},
+			expectedPadding: []padding{{offset: 2, length: 1}, {offset: 6, length: 2}},
+		},
+		{
+			name:        "Error: padding not configured",
+			inputBytes:  []byte{0x90, 0x01, 0xFF, 0xFF},
+			padding:     []byte{0x00},
+			expected:    BerTLVs{},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var found []padding
+
+			received, err := ParseWithOptions(tc.inputBytes, ParseOptions{
+				Padding: tc.padding,
+				OnPadding: func(offset int, length int) {
+					found = append(found, padding{offset: offset, length: length})
+				},
+			})
+			if err != nil && !tc.expectError {
+				t.Errorf("Expected: no error, got: error(%v)", err.Error())
+
+				return
+			}
+
+			if err == nil && tc.expectError {
+				t.Errorf("Expected: error, got: no error")
+
+				return
+			}
+
+			if !cmp.Equal(received, tc.expected, cmpParsed) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+
+			if !tc.expectError && !cmp.Equal(found, tc.expectedPadding, cmp.AllowUnexported(padding{})) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expectedPadding, found)
+			}
+		})
+	}
+}
+
+func TestParseFirstWithOptions(t *testing.T) {
+	input := []byte{0x00, 0x00, 0x90, 0x01, 0xFF, 0x00, 0x91, 0x00}
+
+	received, lenParsed, rest, err := ParseFirstWithOptions(input, ParseOptions{Padding: []byte{0x00}})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	expected := BerTLV{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}}
+	if !cmp.Equal(received, expected, cmpParsed) {
+		t.Errorf("Expected: '%v', got: '%v'", expected, received)
+	}
+
+	if lenParsed != 5 {
+		t.Errorf("Expected: 5 bytes parsed, got: %d", lenParsed)
+	}
+
+	if !bytes.Equal(rest, input[5:]) {
+		t.Errorf("Expected: '%X', got: '%X'", input[5:], rest)
+	}
+
+	if _, _, _, err = ParseFirstWithOptions([]byte{0x00, 0x00}, ParseOptions{Padding: []byte{0x00}}); err == nil {
+		t.Errorf("Expected: error, got: no error")
+	}
+}
+
+func TestParseWithOptions_Form(t *testing.T) {
+	form := func(tag BerTag) Form {
+		switch {
+		case bytes.Equal(tag, NewTwoByteTag(0xDF, 0x01)):
+			return FormConstructed
+		case bytes.Equal(tag, NewOneByteTag(0x21)):
+			return FormPrimitive
+		default:
+			return FormDefault
+		}
+	}
+
+	tests := []struct {
+		name        string
+		inputBytes  []byte
+		form        func(tag BerTag) Form
+		expected    BerTLVs
+		expectError bool
+	}{
+		{
+			name:       "primitive tag parsed as constructed at any depth",
+			inputBytes: []byte{0x70, 0x06, 0xDF, 0x01, 0x03, 0x90, 0x01, 0xFF},
+			form:       form,
+			expected: BerTLVs{
+				{
+					Tag:   NewOneByteTag(0x70),
+					Value: []byte{0xDF, 0x01, 0x03, 0x90, 0x01, 0xFF},
+					children: []BerTLV{
+						{
+							Tag:   NewTwoByteTag(0xDF, 0x01),
+							Value: []byte{0x90, 0x01, 0xFF},
+							children: []BerTLV{
+								{Tag: NewOneByteTag(0x90), Value: []byte{0xFF}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:       "constructed tag treated as primitive",
+			inputBytes: []byte{0x61, 0x05, 0x21, 0x03, 0x01, 0x02, 0x03},
+			form:       form,
+			expected: BerTLVs{
+				{
+					Tag:   NewOneByteTag(0x61),
+					Value: []byte{0x21, 0x03, 0x01, 0x02, 0x03},
+					children: []BerTLV{
+						{Tag: NewOneByteTag(0x21), Value: []byte{0x01, 0x02, 0x03}},
+					},
+				},
+			},
+		},
+		{
+			name:        "Error: constructed tag without override",
+			inputBytes:  []byte{0x61, 0x05, 0x21, 0x03, 0x01, 0x02, 0x03},
+			form:        nil,
+			expected:    BerTLVs{},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := ParseWithOptions(tc.inputBytes, ParseOptions{Form: tc.form})
+			if err != nil && !tc.expectError {
+				t.Errorf("Expected: no error, got: error(%v)", err.Error())
+
+				return
+			}
+
+			if err == nil && tc.expectError {
+				t.Errorf("Expected: error, got: no error")
+
+				return
+			}
+
+			if !cmp.Equal(received, tc.expected, cmpParsed) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestParseWithOptions_CopyInput(t *testing.T) {
+	input := []byte{0x00, 0xE1, 0x03, 0x5A, 0x01, 0x12, 0x9F, 0x01, 0x00, 0x5F, 0x20, 0x01, 0x41}
+	expected, err := ParseWithOptions(append([]byte{}, input...), ParseOptions{Padding: []byte{0x00}})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	opts := ParseOptions{Padding: []byte{0x00}, CopyInput: true}
+
+	received, err := ParseWithOptions(input, opts)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	first, _, _, err := ParseFirstWithOptions(input, opts)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	lenient, problems := ParseLenient(input, opts)
+	if len(problems) != 0 {
+		t.Fatalf("Expected: no problems, got: %v", problems)
+	}
+
+	// reuse the input buffer
+	for i := range input {
+		input[i] = 0xFF
+	}
+
+	if !cmp.Equal(received, expected, cmp.AllowUnexported(BerTLV{})) {
+		t.Errorf("Expected: '%v', got: '%v'", expected, received)
+	}
+
+	if !cmp.Equal(first, expected[0], cmp.AllowUnexported(BerTLV{})) {
+		t.Errorf("Expected: '%v', got: '%v'", expected[0], first)
+	}
+
+	if !cmp.Equal(lenient, expected, cmp.AllowUnexported(BerTLV{})) {
+		t.Errorf("Expected: '%v', got: '%v'", expected, lenient)
+	}
+
+	// without CopyInput, the values refer to the input
+	reused := []byte{0x5F, 0x20, 0x01, 0x41}
+
+	aliased, err := Parse(reused)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	reused[3] = 0x42
+	if aliased[0].Value[0] != 0x42 {
+		t.Errorf("Expected: value referring to the input, got: '%X'", aliased[0].Value)
+	}
+}
@@ -0,0 +1,104 @@
+package bertlv
+
+import (
+	"encoding/asn1"
+
+	"github.com/pkg/errors"
+)
+
+// NewBerTLVFromRawValue returns a new BerTLV for an asn1.RawValue, e.g. a field of a struct that was unmarshalled with
+// encoding/asn1. If FullBytes is set, it is parsed and must contain exactly one BER-TLV object. Otherwise the tag is
+// created from Class, Tag and IsCompound and the BerTLV is created with NewBerTLV from Bytes.
+func NewBerTLVFromRawValue(rv asn1.RawValue) (*BerTLV, error) {
+	if len(rv.FullBytes) > 0 {
+		tlv, err := ParseRawContent(rv.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return &tlv, nil
+	}
+
+	if rv.Class < 0 || rv.Class > 3 || rv.Tag < 0 {
+		return nil, errors.Errorf("%s: invalid class %d or tag %d", packageTag, rv.Class, rv.Tag)
+	}
+
+	tag := NewTag(Class(rv.Class), rv.IsCompound, uint64(rv.Tag))
+	if err := tag.CheckEncoding(); err != nil {
+		return nil, errors.Wrapf(err, "%s: tag number %d", packageTag, rv.Tag)
+	}
+
+	return NewBerTLV(tag, rv.Bytes)
+}
+
+// ParseRawContent parses raw, which must contain exactly one BER-TLV object, and returns it.
+// Use it for DER encoded fields of the standard library like asn1.RawContent or the raw fields of
+// x509.Certificate, e.g. RawTBSCertificate, RawSubject or RawSubjectPublicKeyInfo.
+func ParseRawContent(raw asn1.RawContent) (BerTLV, error) {
+	tlv, _, rest, err := ParseFirst(raw)
+	if err != nil {
+		return BerTLV{}, err
+	}
+
+	if len(rest) > 0 {
+		return BerTLV{}, errors.Errorf("%s: %d bytes of trailing data", packageTag, len(rest))
+	}
+
+	return tlv, nil
+}
+
+// RawValue returns the BerTLV as asn1.RawValue that can be marshalled with encoding/asn1.
+// FullBytes are encoded with PreserveEncoding, so BerTLV objects that have not been modified after parsing
+// are reproduced byte-exact. Returns an error for pseudo-objects for malformed input (see BerTLV.Unparsed)
+// and BerTLV objects with an invalid tag.
+func (ber BerTLV) RawValue() (asn1.RawValue, error) {
+	if ber.unparsed != nil {
+		return asn1.RawValue{}, errors.Wrap(ber.unparsed, packageTag+": unparsed bytes")
+	}
+
+	if err := ber.Tag.CheckEncoding(); err != nil {
+		return asn1.RawValue{}, errors.Wrap(err, packageTag)
+	}
+
+	return asn1.RawValue{
+		Class:      int(ber.Tag.Class()),
+		Tag:        int(ber.Tag.Number()),
+		IsCompound: ber.Tag.IsConstructed(),
+		Bytes:      ber.Value,
+		FullBytes:  ber.Encode(PreserveEncoding),
+	}, nil
+}
+
+// RawContent returns the BerTLV encoded with PreserveEncoding as asn1.RawContent.
+func (ber BerTLV) RawContent() asn1.RawContent {
+	return ber.Encode(PreserveEncoding)
+}
+
+// CertificateExtensions parses a DER encoded TBSCertificate of RFC 5280, e.g. x509.Certificate.RawTBSCertificate,
+// and returns its extensions, i.e. the Extension SEQUENCEs contained in the explicitly tagged [3] field.
+// The extension ID can be decoded with ObjectIdentifier from the first child of each Extension and the extension
+// value with OctetString from the last child.
+//
+// Returns nil if the TBSCertificate has no extensions or an error if it can not be parsed.
+func CertificateExtensions(tbs []byte) (BerTLVs, error) {
+	tlv, err := ParseRawContent(tbs)
+	if err != nil {
+		return nil, err
+	}
+
+	if !tlv.Tag.IsConstructed() {
+		return nil, errors.Errorf("%s: TBSCertificate must be a SEQUENCE, got tag %02X", packageTag, []byte(tlv.Tag))
+	}
+
+	explicit := tlv.FirstChild(NewTag(ContextSpecific, true, 3))
+	if explicit == nil {
+		return nil, nil
+	}
+
+	extensions := explicit.FirstChild(NewTag(Universal, true, UniversalSequence))
+	if extensions == nil {
+		return nil, errors.Errorf("%s: extensions of TBSCertificate must be a SEQUENCE", packageTag)
+	}
+
+	return extensions.Children(nil), nil
+}
@@ -0,0 +1,317 @@
+package bertlv
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testExtensionID = asn1.ObjectIdentifier{1, 2, 3, 4}
+
+func testCertificate(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(42),
+		Subject:         pkix.Name{CommonName: "bertlv"},
+		NotBefore:       time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:        time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		SubjectKeyId:    []byte{0x01, 0x02, 0x03, 0x04},
+		ExtraExtensions: []pkix.Extension{{Id: testExtensionID, Value: []byte{0x04, 0x01, 0xAA}}},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	return cert
+}
+
+func TestNewBerTLVFromRawValue(t *testing.T) {
+	var unmarshalled asn1.RawValue
+
+	if _, err := asn1.Unmarshal([]byte{0xA0, 0x03, 0x02, 0x01, 0x05}, &unmarshalled); err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	tests := []struct {
+		name        string
+		rv          asn1.RawValue
+		expected    []byte
+		expectError bool
+	}{
+		{
+			name:     "full bytes",
+			rv:       unmarshalled,
+			expected: []byte{0xA0, 0x03, 0x02, 0x01, 0x05},
+		},
+		{
+			name:     "class and tag",
+			rv:       asn1.RawValue{Class: asn1.ClassApplication, Tag: 32, IsCompound: true, Bytes: []byte{0x02, 0x01, 0x05}},
+			expected: []byte{0x7F, 0x20, 0x03, 0x02, 0x01, 0x05},
+		},
+		{
+			name:     "primitive",
+			rv:       asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagOctetString, Bytes: []byte{0x01}},
+			expected: []byte{0x04, 0x01, 0x01},
+		},
+		{
+			name:        "Error: trailing data",
+			rv:          asn1.RawValue{FullBytes: []byte{0x04, 0x01, 0x01, 0x90, 0x00}},
+			expectError: true,
+		},
+		{
+			name:        "Error: invalid class",
+			rv:          asn1.RawValue{Class: 4, Tag: 1},
+			expectError: true,
+		},
+		{
+			name:        "Error: tag number too large",
+			rv:          asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 16384},
+			expectError: true,
+		},
+		{
+			name:        "Error: invalid children",
+			rv:          asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: []byte{0x02, 0x02, 0x05}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := NewBerTLVFromRawValue(tc.rv)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if err == nil && !bytes.Equal(received.Bytes(), tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, received.Bytes())
+			}
+		})
+	}
+}
+
+func TestBerTLV_RawValue(t *testing.T) {
+	input := []byte{0x7F, 0x20, 0x81, 0x03, 0x02, 0x01, 0x05}
+
+	tlvs, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	received, err := tlvs[0].RawValue()
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	expected := asn1.RawValue{
+		Class:      asn1.ClassApplication,
+		Tag:        32,
+		IsCompound: true,
+		Bytes:      []byte{0x02, 0x01, 0x05},
+		FullBytes:  input,
+	}
+
+	if diff := cmp.Diff(expected, received); diff != "" {
+		t.Errorf("Mismatch (-want +got):\n%s", diff)
+	}
+
+	// encoding/asn1 marshals the full bytes, so the non-minimal length is kept
+	marshalled, err := asn1.Marshal(received)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	if !bytes.Equal(marshalled, input) {
+		t.Errorf("Expected: '%X', got: '%X'", input, marshalled)
+	}
+
+	if !bytes.Equal(tlvs[0].RawContent(), input) {
+		t.Errorf("Expected: '%X', got: '%X'", input, tlvs[0].RawContent())
+	}
+
+	unparsed, _ := ParseLenient([]byte{0x04, 0x05, 0x01}, ParseOptions{})
+	if _, err = unparsed[0].RawValue(); err == nil {
+		t.Errorf("Expected: error, got: nil")
+	}
+
+	if _, err = (BerTLV{Tag: NewOneByteTag(0x1F)}).RawValue(); err == nil {
+		t.Errorf("Expected: error, got: nil")
+	}
+}
+
+func TestCertificateExtensions(t *testing.T) {
+	cert := testCertificate(t)
+
+	extensions, err := CertificateExtensions(cert.RawTBSCertificate)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	if len(extensions) != len(cert.Extensions) {
+		t.Fatalf("Expected: %d extensions, got: %d", len(cert.Extensions), len(extensions))
+	}
+
+	for i, extension := range extensions {
+		id, err := extension.FirstChild(nil).ObjectIdentifier()
+		if err != nil {
+			t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+		}
+
+		if !id.Equal(cert.Extensions[i].Id) {
+			t.Errorf("Expected: '%v', got: '%v'", cert.Extensions[i].Id, id)
+		}
+
+		children := extension.Children(nil)
+
+		value, err := children[len(children)-1].OctetString()
+		if err != nil || !bytes.Equal(value, cert.Extensions[i].Value) {
+			t.Errorf("Expected: '%X', got: '%X', error(%v)", cert.Extensions[i].Value, value, err)
+		}
+	}
+
+	tbs, err := ParseRawContent(cert.RawTBSCertificate)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	rv, err := tbs.RawValue()
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	marshalled, err := asn1.Marshal(rv)
+	if err != nil || !bytes.Equal(marshalled, cert.RawTBSCertificate) {
+		t.Errorf("Expected: unchanged TBSCertificate, got: error(%v)", err)
+	}
+}
+
+func TestCertificateExtensions_Patch(t *testing.T) {
+	cert := testCertificate(t)
+
+	tbs, err := ParseRawContent(cert.RawTBSCertificate)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	extensions, err := CertificateExtensions(cert.RawTBSCertificate)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	// replace the value of the test extension and rebuild the TBSCertificate
+	bu := &Builder{}
+	bu.Begin(NewTag(ContextSpecific, true, 3)).Begin(NewTag(Universal, true, UniversalSequence))
+
+	for _, extension := range extensions {
+		if id, _ := extension.FirstChild(nil).ObjectIdentifier(); id.Equal(testExtensionID) {
+			bu.AddConstructed(extension.Tag, func(bu *Builder) {
+				bu.AddObjectIdentifier(NewTag(Universal, false, UniversalObjectIdentifier), testExtensionID).
+					AddOctetString(NewTag(Universal, false, UniversalOctetString), []byte{0x04, 0x02, 0xBB, 0xCC})
+			})
+
+			continue
+		}
+
+		bu.AddTLV(extension)
+	}
+
+	bu.End().End()
+
+	var children BerTLVs
+
+	for _, child := range tbs.Children(nil) {
+		if child.Tag.ID() == NewTag(ContextSpecific, true, 3).ID() {
+			continue
+		}
+
+		children = append(children, child)
+	}
+
+	patched, err := NewBerTLV(tbs.Tag, append(children.Bytes(), bu.Bytes()...))
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	rv, err := patched.RawValue()
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	// re-marshal with encoding/asn1 as part of a certificate and parse it with crypto/x509
+	marshalled, err := asn1.Marshal(struct {
+		TBS       asn1.RawValue
+		Algorithm pkix.AlgorithmIdentifier
+		Signature asn1.BitString
+	}{
+		TBS:       rv,
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+		Signature: asn1.BitString{Bytes: cert.Signature, BitLength: 8 * len(cert.Signature)},
+	})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	parsed, err := x509.ParseCertificate(marshalled)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	for _, extension := range parsed.Extensions {
+		if extension.Id.Equal(testExtensionID) && !bytes.Equal(extension.Value, []byte{0x04, 0x02, 0xBB, 0xCC}) {
+			t.Errorf("Expected: '%X', got: '%X'", []byte{0x04, 0x02, 0xBB, 0xCC}, extension.Value)
+		}
+	}
+
+	if !bytes.Equal(parsed.SubjectKeyId, cert.SubjectKeyId) {
+		t.Errorf("Expected: '%X', got: '%X'", cert.SubjectKeyId, parsed.SubjectKeyId)
+	}
+}
+
+func TestCertificateExtensions_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		expectNil   bool
+		expectError bool
+	}{
+		{name: "no extensions", input: []byte{0x30, 0x03, 0x02, 0x01, 0x01}, expectNil: true},
+		{name: "Error: not a sequence", input: []byte{0x02, 0x01, 0x01}, expectError: true},
+		{name: "Error: extensions not a sequence", input: []byte{0x30, 0x05, 0xA3, 0x03, 0x02, 0x01, 0x01}, expectError: true},
+		{name: "Error: malformed", input: []byte{0x30, 0x05, 0x02, 0x01}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := CertificateExtensions(tc.input)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if tc.expectNil && received != nil {
+				t.Errorf("Expected: nil, got: '%v'", received)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package bertlv
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// Redaction describes how the value of a BerTLV is masked in redacted output. The value is shown as hex digits and
+// all digits except the first KeepFirst and the last KeepLast digits are replaced by '*'. The zero value masks the
+// whole value. Values with not more than KeepFirst+KeepLast digits are masked as a whole.
+type Redaction struct {
+	KeepFirst int // Number of leading hex digits that are shown, e.g. 6 for the BIN of a PAN.
+	KeepLast  int // Number of trailing hex digits that are shown.
+}
+
+// RedactionPolicy maps tags to the Redaction that is applied to the values of BerTLV objects with that tag.
+// The value of a constructed object is masked as a whole, including all child objects. Objects nested in the value
+// of other objects are redacted as well. A nil or empty policy masks nothing.
+//
+// Redaction is opt-in: BerTLV.String, BerTLV.MarshalJSON and Dump output all values unless a policy is passed,
+// only BerTLV.LogValue applies DefaultRedactionPolicy. A policy must not be modified concurrently with its use.
+type RedactionPolicy map[TagID]Redaction
+
+// DefaultRedactionPolicy returns a new policy that masks the cardholder data of EMV: the PAN ('5A') except for the
+// first six and last four digits, track data ('56', '57', '9F1F', '9F20', '9F6B') and cardholder names ('5F20',
+// '9F0B'). The returned policy belongs to the caller, e.g. to add the tags of keys or PIN blocks.
+func DefaultRedactionPolicy() RedactionPolicy {
+	p := make(RedactionPolicy, len(defaultRedactionPolicy))
+	for id, r := range defaultRedactionPolicy {
+		p[id] = r
+	}
+
+	return p
+}
+
+// defaultRedactionPolicy is applied by BerTLV.LogValue and copied by DefaultRedactionPolicy. It is never modified.
+var defaultRedactionPolicy = RedactionPolicy{
+	0x5A:   {KeepFirst: 6, KeepLast: 4},
+	0x56:   {},
+	0x57:   {},
+	0x9F1F: {},
+	0x9F20: {},
+	0x9F6B: {},
+	0x5F20: {},
+	0x9F0B: {},
+}
+
+// Redact returns ber wrapped with the policy for output, e.g. as argument of fmt, json.Marshal or slog.
+func (p RedactionPolicy) Redact(ber BerTLV) RedactedBerTLV {
+	return RedactedBerTLV{tlv: ber, policy: p}
+}
+
+// RedactedBerTLV is a BerTLV whose String, MarshalJSON and LogValue (Go 1.21+) mask values according to
+// a RedactionPolicy, see RedactionPolicy.Redact.
+type RedactedBerTLV struct {
+	tlv    BerTLV
+	policy RedactionPolicy
+}
+
+// String returns the BerTLV like RedactionPolicy.String.
+func (r RedactedBerTLV) String() string {
+	return r.policy.String(r.tlv)
+}
+
+// MarshalJSON implements json.Marshaler, see RedactionPolicy.JSON.
+func (r RedactedBerTLV) MarshalJSON() ([]byte, error) {
+	return r.policy.JSON(r.tlv)
+}
+
+// String returns the BerTLV hex encoded (upper-case) like BerTLV.Bytes with masked values according to the policy.
+// Since masked digits are replaced by '*', the result is not necessarily valid hex.
+func (p RedactionPolicy) String(ber BerTLV) string {
+	b := ber.Bytes()
+	if ber.unparsed != nil {
+		return strings.ToUpper(hex.EncodeToString(b))
+	}
+
+	value := p.value(ber)
+
+	// Bytes truncates values that are too long
+	valueLen := len(ber.Value)
+	if valueLen > 65535 {
+		valueLen = 65535
+		value = value[:2*valueLen]
+	}
+
+	return strings.ToUpper(hex.EncodeToString(b[:len(b)-valueLen])) + string(value)
+}
+
+// value returns the hex digits of the value of ber with masked values according to the policy.
+func (p RedactionPolicy) value(ber BerTLV) []byte {
+	digits := []byte(strings.ToUpper(hex.EncodeToString(ber.Value)))
+
+	if len(p) > 0 && ber.unparsed == nil {
+		p.redact(digits, ber)
+	}
+
+	return digits
+}
+
+// redact masks the hex digits of the value of ber according to the policy.
+func (p RedactionPolicy) redact(digits []byte, ber BerTLV) {
+	if r, ok := p[ber.Tag.ID()]; ok {
+		r.mask(digits)
+
+		return
+	}
+
+	children, base := redactionChildren(ber)
+
+	for _, child := range children {
+		if !p.applies(child) {
+			continue
+		}
+
+		start := child.offset + len(child.header) - base
+		end := start + len(child.Value)
+
+		// mask the whole value if the child can not be located, e.g. because the value was modified after parsing
+		if start < 0 || end > len(ber.Value) || !bytes.Equal(ber.Value[start:end], child.Value) {
+			Redaction{}.mask(digits)
+
+			return
+		}
+
+		p.redact(digits[2*start:2*end], child)
+	}
+}
+
+// applies returns true if the policy masks the value of ber or of one of its child objects.
+func (p RedactionPolicy) applies(ber BerTLV) bool {
+	if ber.unparsed != nil {
+		return false
+	}
+
+	if _, ok := p[ber.Tag.ID()]; ok {
+		return true
+	}
+
+	children, _ := redactionChildren(ber)
+
+	for _, child := range children {
+		if p.applies(child) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactionChildren returns the child objects of a constructed BerTLV and the offset of its value that the offsets
+// of the children refer to. If the children have not been parsed, e.g. for a BerTLV created as struct literal,
+// they are parsed from the value, so nested sensitive objects are found as well.
+func redactionChildren(ber BerTLV) ([]BerTLV, int) {
+	if ber.children != nil || !ber.Tag.IsConstructed() || len(ber.Value) == 0 {
+		return ber.children, ber.offset + len(ber.header)
+	}
+
+	p := parser{}
+
+	children, _, err := p.parse(ber.Value, 0, false)
+	if err != nil {
+		return nil, 0
+	}
+
+	return children, 0
+}
+
+// mask replaces the hex digits that are not kept with '*'.
+func (r Redaction) mask(digits []byte) {
+	first, last := r.KeepFirst, r.KeepLast
+	if first < 0 {
+		first = 0
+	}
+
+	if last < 0 {
+		last = 0
+	}
+
+	if first+last >= len(digits) {
+		first, last = 0, 0
+	}
+
+	for i := first; i < len(digits)-last; i++ {
+		digits[i] = '*'
+	}
+}
+
+// jsonBerTLV is the JSON representation of a BerTLV.
+type jsonBerTLV struct {
+	Tag      string           `json:"tag,omitempty"`
+	Value    string           `json:"value,omitempty"`
+	Children []RedactedBerTLV `json:"children,omitempty"`
+	Unparsed string           `json:"unparsed,omitempty"`
+}
+
+// JSON returns the BerTLV encoded as JSON like BerTLV.MarshalJSON with values masked according to the policy,
+// e.g. {"tag":"5A","value":"541333******0434"}. Constructed objects whose value is masked are encoded with their
+// value instead of their children.
+func (p RedactionPolicy) JSON(ber BerTLV) ([]byte, error) {
+	if ber.unparsed != nil {
+		return json.Marshal(jsonBerTLV{Unparsed: strings.ToUpper(hex.EncodeToString(ber.Value))})
+	}
+
+	j := jsonBerTLV{Tag: strings.ToUpper(hex.EncodeToString(ber.Tag))}
+
+	if _, ok := p[ber.Tag.ID()]; !ok && len(ber.children) > 0 {
+		j.Children = make([]RedactedBerTLV, len(ber.children))
+		for i, child := range ber.children {
+			j.Children[i] = p.Redact(child)
+		}
+	} else {
+		j.Value = string(p.value(ber))
+	}
+
+	return json.Marshal(j)
+}
+
+// MarshalJSON implements json.Marshaler. The BerTLV is encoded as object with the hex encoded tag and value, e.g.
+// {"tag":"5A","value":"5413330089010434"}. Constructed objects whose children were parsed are encoded with their
+// children instead of their value, e.g. {"tag":"70","children":[...]}, pseudo-objects for malformed input with
+// their bytes as "unparsed". Values are not masked, use RedactionPolicy.JSON or RedactionPolicy.Redact for that.
+func (ber BerTLV) MarshalJSON() ([]byte, error) {
+	return RedactionPolicy(nil).JSON(ber)
+}
@@ -0,0 +1,32 @@
+//go:build go1.21
+// +build go1.21
+
+package bertlv
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// LogValue implements slog.LogValuer. The BerTLV is logged as hex string like String with values of sensitive
+// objects masked according to DefaultRedactionPolicy. Use RedactionPolicy.Redact to log with another policy.
+func (ber BerTLV) LogValue() slog.Value {
+	return slog.StringValue(defaultRedactionPolicy.String(ber))
+}
+
+// LogValue implements slog.LogValuer. The BerTLV is logged as hex string like String.
+func (r RedactedBerTLV) LogValue() slog.Value {
+	return slog.StringValue(r.String())
+}
+
+// LogValue implements slog.LogValuer. BerTLVs are logged as concatenated hex strings of their BerTLV objects,
+// see BerTLV.LogValue.
+func (t BerTLVs) LogValue() slog.Value {
+	var sb strings.Builder
+
+	for _, tlv := range t {
+		sb.WriteString(defaultRedactionPolicy.String(tlv))
+	}
+
+	return slog.StringValue(sb.String())
+}
@@ -0,0 +1,34 @@
+//go:build go1.21
+// +build go1.21
+
+package bertlv
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestBerTLV_LogValue(t *testing.T) {
+	tlvs, err := Parse([]byte{0x5A, 0x08, 0x54, 0x13, 0x33, 0x00, 0x89, 0x01, 0x04, 0x34, 0x90, 0x01, 0x01})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+
+		return a
+	}}))
+
+	logger.Info("read", "pan", tlvs[0], "record", tlvs, "status", RedactionPolicy{0x90: {}}.Redact(tlvs[1]))
+
+	expected := `{"level":"INFO","msg":"read","pan":"5A08541333******0434","record":"5A08541333******0434900101","status":"9001**"}`
+	if received := strings.TrimSpace(buf.String()); received != expected {
+		t.Errorf("Expected: '%s', got: '%s'", expected, received)
+	}
+}
@@ -0,0 +1,156 @@
+package bertlv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRedactionPolicy_String(t *testing.T) {
+	parsed, err := Parse([]byte{0x70, 0x0E, 0x5A, 0x08, 0x54, 0x13, 0x33, 0x00, 0x89, 0x01, 0x04, 0x34, 0x5F, 0x20, 0x01, 0x41})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	modified := parsed[0].Clone()
+	modified.Value = append([]byte{0x90, 0x00}, modified.Value...)
+
+	tests := []struct {
+		name     string
+		policy   RedactionPolicy
+		berTLV   BerTLV
+		expected string
+	}{
+		{
+			name:     "pan",
+			policy:   DefaultRedactionPolicy(),
+			berTLV:   BerTLV{Tag: NewOneByteTag(0x5A), Value: []byte{0x54, 0x13, 0x33, 0x00, 0x89, 0x01, 0x04, 0x34}},
+			expected: "5A08541333******0434",
+		},
+		{
+			name:     "short value masked as a whole",
+			policy:   RedactionPolicy{0x5A: {KeepFirst: 6, KeepLast: 4}},
+			berTLV:   BerTLV{Tag: NewOneByteTag(0x5A), Value: []byte{0x54, 0x13, 0x33, 0x00, 0x89}},
+			expected: "5A05**********",
+		},
+		{
+			name:     "nested",
+			policy:   DefaultRedactionPolicy(),
+			berTLV:   parsed[0],
+			expected: "700E5A08541333******04345F2001**",
+		},
+		{
+			name:     "children not parsed",
+			policy:   RedactionPolicy{0x80: {KeepLast: 2}},
+			berTLV:   BerTLV{Tag: NewOneByteTag(0xA1), Value: []byte{0x30, 0x04, 0x80, 0x02, 0x12, 0x34, 0x81, 0x01, 0x56}},
+			expected: "A10930048002**34810156",
+		},
+		{
+			name:     "modified value masked as a whole",
+			policy:   DefaultRedactionPolicy(),
+			berTLV:   modified,
+			expected: "7010" + "********************************",
+		},
+		{
+			name:     "constructed masked as a whole",
+			policy:   RedactionPolicy{0x70: {}},
+			berTLV:   parsed[0],
+			expected: "700E" + "****************************",
+		},
+		{
+			name:     "no policy",
+			policy:   nil,
+			berTLV:   parsed[0],
+			expected: "700E5A0854133300890104345F200141",
+		},
+		{
+			name:     "unparsed",
+			policy:   DefaultRedactionPolicy(),
+			berTLV:   BerTLV{Value: []byte{0x5A, 0x08}, unparsed: errors.New("malformed")},
+			expected: "5A08",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if received := tc.policy.String(tc.berTLV); received != tc.expected {
+				t.Errorf("Expected: '%s', got: '%s'", tc.expected, received)
+			}
+		})
+	}
+
+	// redaction is opt-in
+	if received := parsed[0].String(); received != "700E5A0854133300890104345F200141" {
+		t.Errorf("Expected: '700E5A0854133300890104345F200141', got: '%s'", received)
+	}
+
+	if received := fmt.Sprint(DefaultRedactionPolicy().Redact(parsed[0])); received != "700E5A08541333******04345F2001**" {
+		t.Errorf("Expected: redacted string, got: '%s'", received)
+	}
+}
+
+func TestDefaultRedactionPolicy(t *testing.T) {
+	p := DefaultRedactionPolicy()
+	p[0x9F02] = Redaction{}
+
+	if _, ok := DefaultRedactionPolicy()[0x9F02]; ok {
+		t.Errorf("Expected: new policy for every call")
+	}
+}
+
+func TestBerTLV_MarshalJSON(t *testing.T) {
+	parsed, err := Parse([]byte{0x70, 0x0E, 0x5A, 0x08, 0x54, 0x13, 0x33, 0x00, 0x89, 0x01, 0x04, 0x34, 0x9F, 0x02, 0x01, 0x01, 0x57, 0x02, 0x12, 0x34})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	unparsed, _ := ParseLenient([]byte{0x90, 0x01, 0x01, 0x5A}, ParseOptions{})
+
+	policy := DefaultRedactionPolicy()
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{
+			name:     "constructed",
+			input:    parsed,
+			expected: `[{"tag":"70","children":[{"tag":"5A","value":"5413330089010434"},{"tag":"9F02","value":"01"}]},{"tag":"57","value":"1234"}]`,
+		},
+		{
+			name:     "redacted",
+			input:    []RedactedBerTLV{policy.Redact(parsed[0]), policy.Redact(parsed[1])},
+			expected: `[{"tag":"70","children":[{"tag":"5A","value":"541333******0434"},{"tag":"9F02","value":"01"}]},{"tag":"57","value":"****"}]`,
+		},
+		{
+			name:     "redacted constructed",
+			input:    RedactionPolicy{0x70: {}}.Redact(parsed[0]),
+			expected: `{"tag":"70","value":"****************************"}`,
+		},
+		{
+			name:     "unparsed",
+			input:    unparsed,
+			expected: `[{"tag":"90","value":"01"},{"unparsed":"5A"}]`,
+		},
+		{
+			name:     "empty",
+			input:    BerTLV{Tag: NewOneByteTag(0x90)},
+			expected: `{"tag":"90"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := json.Marshal(tc.input)
+			if err != nil {
+				t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+			}
+
+			if string(received) != tc.expected {
+				t.Errorf("Expected: '%s', got: '%s'", tc.expected, received)
+			}
+		})
+	}
+}
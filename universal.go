@@ -0,0 +1,976 @@
+package bertlv
+
+import (
+	"encoding/asn1"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Tag numbers of the universal class types of ITU-T X.680. Tags of universal types can be created with NewTag,
+// e.g. NewTag(Universal, false, UniversalInteger).
+//
+// The encoders and decoders of this file take the tag as argument instead of using the universal tag,
+// so that they can also be used for implicitly tagged types like [0] IMPLICIT INTEGER.
+// BOOLEAN is encoded by Builder.AddBool and decoded by BerTLV.Bool.
+const (
+	UniversalBoolean          = 1
+	UniversalInteger          = 2
+	UniversalBitString        = 3
+	UniversalOctetString      = 4
+	UniversalNull             = 5
+	UniversalObjectIdentifier = 6
+	UniversalReal             = 9
+	UniversalEnumerated       = 10
+	UniversalUTF8String       = 12
+	UniversalRelativeOID      = 13
+	UniversalSequence         = 16
+	UniversalSet              = 17
+	UniversalPrintableString  = 19
+	UniversalIA5String        = 22
+	UniversalUTCTime          = 23
+	UniversalGeneralizedTime  = 24
+	UniversalBMPString        = 30
+)
+
+// CERSegmentSize is the number of contents bytes of the segments of strings in constructed form as required by CER.
+const CERSegmentSize = 1000
+
+// AddInteger adds the given tag with v encoded as ASN.1 INTEGER, i.e. in two's complement with the minimal number
+// of bytes, to the Builder.
+func (bu *Builder) AddInteger(tag BerTag, v *big.Int) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	return bu.addTemporary("AddInteger", tag, encodeInteger(v))
+}
+
+// AddEnumerated adds the given tag with v encoded as ASN.1 ENUMERATED to the Builder.
+func (bu *Builder) AddEnumerated(tag BerTag, v int64) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	b, _ := encodeInt(v, 0)
+
+	return bu.addTemporary("AddEnumerated", tag, b)
+}
+
+// AddNull adds the given tag with an empty value as ASN.1 NULL to the Builder.
+func (bu *Builder) AddNull(tag BerTag) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	return bu.addBytes("AddNull", tag, nil)
+}
+
+// AddObjectIdentifier adds the given tag with oid encoded as ASN.1 OBJECT IDENTIFIER to the Builder.
+// An error is recorded if oid has less than two arcs, a negative arc, an arc that exceeds 2147483647 or invalid
+// first arcs.
+func (bu *Builder) AddObjectIdentifier(tag BerTag, oid asn1.ObjectIdentifier) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	b, err := encodeObjectIdentifier(oid)
+	if err != nil {
+		return bu.fail("AddObjectIdentifier", err)
+	}
+
+	return bu.addTemporary("AddObjectIdentifier", tag, b)
+}
+
+// AddRelativeOID adds the given tag with the arcs encoded as ASN.1 RELATIVE-OID to the Builder.
+// An error is recorded if arcs is empty or has a negative arc or an arc that exceeds 2147483647.
+func (bu *Builder) AddRelativeOID(tag BerTag, arcs []int) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	if len(arcs) == 0 {
+		return bu.fail("AddRelativeOID", errors.New("relative object identifier must have at least one arc"))
+	}
+
+	b, err := appendSubidentifiers(nil, arcs)
+	if err != nil {
+		return bu.fail("AddRelativeOID", err)
+	}
+
+	return bu.addTemporary("AddRelativeOID", tag, b)
+}
+
+// AddBitString adds the given tag with bs encoded as ASN.1 BIT STRING to the Builder. Unused bits are set to zero.
+// If the value is longer than the segment size set with SegmentStrings, it is encoded in constructed form.
+// An error is recorded if the BitLength of bs does not match the length of its Bytes.
+func (bu *Builder) AddBitString(tag BerTag, bs asn1.BitString) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	b, err := encodeBitString(bs)
+	if err != nil {
+		return bu.fail("AddBitString", err)
+	}
+
+	if bu.segmentSize > 0 && len(b) > bu.segmentSize {
+		bu.addSegments("AddBitString", tag, UniversalBitString, b)
+		wipe(b)
+
+		return bu
+	}
+
+	return bu.addTemporary("AddBitString", tag, b)
+}
+
+// AddOctetString adds the given tag with v as ASN.1 OCTET STRING to the Builder.
+// If v is longer than the segment size set with SegmentStrings, it is encoded in constructed form.
+func (bu *Builder) AddOctetString(tag BerTag, v []byte) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	if bu.segmentSize > 0 && len(v) > bu.segmentSize {
+		return bu.addSegments("AddOctetString", tag, UniversalOctetString, v)
+	}
+
+	return bu.addBytes("AddOctetString", tag, v)
+}
+
+// SegmentStrings sets the maximum number of contents bytes of values added with AddOctetString and AddBitString.
+// Longer values are encoded in constructed form as a series of primitive segments with the universal tag of the type
+// and size contents bytes each, except for the last one. Use CERSegmentSize for the segments required by CER.
+// The tag passed to AddOctetString or AddBitString is changed to constructed form.
+//
+// Segmentation is disabled by default or if size is 0. An error is recorded if size is negative or 1, since
+// segments of a BIT STRING need at least two bytes.
+func (bu *Builder) SegmentStrings(size int) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	if size < 0 || size == 1 {
+		return bu.fail("SegmentStrings", errors.Errorf("invalid segment size %d", size))
+	}
+
+	bu.segmentSize = size
+
+	return bu
+}
+
+// addSegments adds the contents bytes v of a string with the given universal tag number in constructed form.
+// The segments are appended to the buffer directly and the length is filled in like by End, so that no temporary
+// copy of v is left behind.
+func (bu *Builder) addSegments(method string, tag BerTag, number uint64, v []byte) *Builder {
+	if err := tag.CheckEncoding(); err != nil {
+		return bu.fail(method, err)
+	}
+
+	start := len(bu.bytes)
+
+	bu.bytes = append(bu.bytes, constructedTag(tag)...)
+	lenPos := len(bu.bytes)
+	bu.bytes = append(bu.bytes, 0x00)
+	bu.bytes = appendSegments(bu.bytes, number, v, bu.segmentSize)
+
+	if err := bu.fillLen(lenPos); err != nil {
+		// like addBytes, a value that is too long is not added
+		wipe(bu.bytes[start:])
+		bu.bytes = bu.bytes[:start]
+
+		return bu.fail(method, errors.Wrapf(err, "tag %02X", []byte(tag)))
+	}
+
+	return bu
+}
+
+// appendSegments appends the contents bytes v of a string with the given universal tag number as series of primitive
+// segments with at most size contents bytes each to b. The first byte of the contents of a BIT STRING is the number
+// of unused bits, which is kept for the last segment and set to zero for the others.
+func appendSegments(b []byte, number uint64, v []byte, size int) []byte {
+	var unused []byte
+	if number == UniversalBitString {
+		unused, v = v[:1], v[1:]
+	}
+
+	segmentTag := NewTag(Universal, false, number)
+	size -= len(unused)
+
+	for len(v) > 0 {
+		n := size
+		if n > len(v) {
+			n = len(v)
+		}
+
+		b = append(b, segmentTag...)
+		b = appendLen(b, n+len(unused))
+
+		switch {
+		case unused == nil:
+		case n == len(v):
+			b = append(b, unused[0])
+		default:
+			b = append(b, 0x00)
+		}
+
+		b = append(b, v[:n]...)
+		v = v[n:]
+	}
+
+	return b
+}
+
+// constructedTag returns a copy of tag with b6 of the first byte set.
+func constructedTag(tag BerTag) BerTag {
+	return append(BerTag{tag[0] | 0x20}, tag[1:]...)
+}
+
+// AddUTF8String adds the given tag with s as ASN.1 UTF8String to the Builder.
+// An error is recorded if s is not valid UTF-8.
+func (bu *Builder) AddUTF8String(tag BerTag, s string) *Builder {
+	return bu.addString("AddUTF8String", tag, s, checkUTF8String)
+}
+
+// AddPrintableString adds the given tag with s as ASN.1 PrintableString to the Builder.
+// An error is recorded if s contains characters that are not allowed in a PrintableString.
+func (bu *Builder) AddPrintableString(tag BerTag, s string) *Builder {
+	return bu.addString("AddPrintableString", tag, s, checkPrintableString)
+}
+
+// AddIA5String adds the given tag with s as ASN.1 IA5String to the Builder.
+// An error is recorded if s contains characters that are not ASCII.
+func (bu *Builder) AddIA5String(tag BerTag, s string) *Builder {
+	return bu.addString("AddIA5String", tag, s, checkIA5String)
+}
+
+func (bu *Builder) addString(method string, tag BerTag, s string, check func(b []byte) error) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	b := []byte(s)
+
+	if err := check(b); err != nil {
+		wipe(b)
+
+		return bu.fail(method, err)
+	}
+
+	return bu.addTemporary(method, tag, b)
+}
+
+// AddBMPString adds the given tag with s encoded as ASN.1 BMPString (UCS-2 big-endian) to the Builder.
+// An error is recorded if s is not valid UTF-8 or contains characters outside the Basic Multilingual Plane.
+func (bu *Builder) AddBMPString(tag BerTag, s string) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	b := make([]byte, 0, 2*len(s))
+
+	for i, r := range s {
+		if r == utf8.RuneError || r > 0xFFFF || utf16.IsSurrogate(r) {
+			wipe(b)
+
+			return bu.fail("AddBMPString", errors.Errorf("character at index %d can not be encoded in a BMPString", i))
+		}
+
+		b = append(b, byte(r>>8), byte(r))
+	}
+
+	return bu.addTemporary("AddBMPString", tag, b)
+}
+
+// AddUTCTime adds the given tag with t encoded as ASN.1 UTCTime in the format YYMMDDhhmmssZ to the Builder.
+// t is converted to UTC. An error is recorded if the year is not between 1950 and 2049.
+func (bu *Builder) AddUTCTime(tag BerTag, t time.Time) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	t = t.UTC()
+	if t.Year() < 1950 || t.Year() > 2049 {
+		return bu.fail("AddUTCTime", errors.Errorf("year %d can not be encoded as UTCTime", t.Year()))
+	}
+
+	return bu.addTemporary("AddUTCTime", tag, t.AppendFormat(nil, "060102150405Z"))
+}
+
+// AddGeneralizedTime adds the given tag with t encoded as ASN.1 GeneralizedTime in the format YYYYMMDDhhmmss[.f]Z
+// to the Builder. t is converted to UTC, fractional seconds are written without trailing zeros.
+// An error is recorded if the year is not between 0 and 9999.
+func (bu *Builder) AddGeneralizedTime(tag BerTag, t time.Time) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	t = t.UTC()
+	if t.Year() < 0 || t.Year() > 9999 {
+		return bu.fail("AddGeneralizedTime", errors.Errorf("year %d can not be encoded as GeneralizedTime", t.Year()))
+	}
+
+	return bu.addTemporary("AddGeneralizedTime", tag, t.AppendFormat(nil, "20060102150405.999999999Z"))
+}
+
+// AddReal adds the given tag with v encoded as ASN.1 REAL to the Builder.
+// Finite values are encoded in base 2 with an odd mantissa as required by DER, zero has an empty value.
+// Infinity, NaN and negative zero are encoded as special real values.
+func (bu *Builder) AddReal(tag BerTag, v float64) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	return bu.addTemporary("AddReal", tag, encodeReal(v))
+}
+
+// Integer returns the value of the BerTLV decoded as ASN.1 INTEGER.
+// Returns an error if the value is empty or not encoded with the minimal number of bytes.
+func (ber BerTLV) Integer() (*big.Int, error) {
+	if err := ber.checkInteger(); err != nil {
+		return nil, err
+	}
+
+	v := new(big.Int).SetBytes(ber.Value)
+
+	if ber.Value[0]&0x80 != 0 {
+		// two's complement: subtract 2^(8*len)
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*len(ber.Value))))
+	}
+
+	return v, nil
+}
+
+// Enumerated returns the value of the BerTLV decoded as ASN.1 ENUMERATED.
+// Returns an error if the value is empty, not encoded with the minimal number of bytes or longer than 8 bytes.
+func (ber BerTLV) Enumerated() (int64, error) {
+	if err := ber.checkInteger(); err != nil {
+		return 0, err
+	}
+
+	return ber.Int()
+}
+
+func (ber BerTLV) checkInteger() error {
+	if len(ber.Value) == 0 {
+		return errors.Errorf("tag %02X: integer value is empty", []byte(ber.Tag))
+	}
+
+	// the first nine bits must not all be ones or all be zeros
+	if len(ber.Value) > 1 &&
+		((ber.Value[0] == 0x00 && ber.Value[1]&0x80 == 0) || (ber.Value[0] == 0xFF && ber.Value[1]&0x80 != 0)) {
+		return errors.Errorf("tag %02X: integer is not encoded with the minimal number of bytes", []byte(ber.Tag))
+	}
+
+	return nil
+}
+
+// Null checks that the value of the BerTLV is a valid ASN.1 NULL, i.e. empty.
+func (ber BerTLV) Null() error {
+	return ber.checkLength(0, 0)
+}
+
+// ObjectIdentifier returns the value of the BerTLV decoded as ASN.1 OBJECT IDENTIFIER.
+// Returns an error if the value is empty, a subidentifier is not encoded with the minimal number of bytes,
+// is truncated or exceeds maxSubidentifier (2147483647).
+func (ber BerTLV) ObjectIdentifier() (asn1.ObjectIdentifier, error) {
+	arcs, err := ber.subidentifiers()
+	if err != nil {
+		return nil, err
+	}
+
+	// the first subidentifier encodes the first two arcs as 40 * X + Y
+	oid := make(asn1.ObjectIdentifier, len(arcs)+1)
+
+	switch first := arcs[0]; {
+	case first < 40:
+		oid[0], oid[1] = 0, first
+	case first < 80:
+		oid[0], oid[1] = 1, first-40
+	default:
+		oid[0], oid[1] = 2, first-80
+	}
+
+	copy(oid[2:], arcs[1:])
+
+	return oid, nil
+}
+
+// RelativeOID returns the value of the BerTLV decoded as ASN.1 RELATIVE-OID.
+// Returns an error if the value is empty, a subidentifier is not encoded with the minimal number of bytes,
+// is truncated or exceeds maxSubidentifier (2147483647).
+func (ber BerTLV) RelativeOID() ([]int, error) {
+	return ber.subidentifiers()
+}
+
+// maxSubidentifier is the greatest subidentifier of OBJECT IDENTIFIER and RELATIVE-OID values that is decoded and
+// encoded. Limiting subidentifiers to 31 bits ensures that arcs fit an int on all platforms.
+const maxSubidentifier = math.MaxInt32
+
+func (ber BerTLV) subidentifiers() ([]int, error) {
+	if len(ber.Value) == 0 {
+		return nil, errors.Errorf("tag %02X: object identifier value is empty", []byte(ber.Tag))
+	}
+
+	var arcs []int
+
+	for i := 0; i < len(ber.Value); {
+		if ber.Value[i] == 0x80 {
+			return nil, errors.Errorf("tag %02X: subidentifier at byte %d is not encoded with the minimal number of bytes", []byte(ber.Tag), i)
+		}
+
+		arc := 0
+		for {
+			if i == len(ber.Value) {
+				return nil, errors.Errorf("tag %02X: last subidentifier is truncated", []byte(ber.Tag))
+			}
+
+			if arc > maxSubidentifier>>7 {
+				return nil, errors.Errorf("tag %02X: subidentifier at byte %d is too large", []byte(ber.Tag), i)
+			}
+
+			b := ber.Value[i]
+			i++
+
+			arc = arc<<7 | int(b&0x7F)
+
+			if b&0x80 == 0 {
+				break
+			}
+		}
+
+		arcs = append(arcs, arc)
+	}
+
+	return arcs, nil
+}
+
+// BitString returns the value of the BerTLV decoded as ASN.1 BIT STRING.
+// In primitive form, the returned Bytes refer to the value of the BerTLV and unused bits are returned as encoded.
+// In constructed form, the primitive segments are concatenated like in OctetString and only the last segment may
+// have unused bits. Returns an error if the value is empty, has more than 7 unused bits, indicates unused bits without
+// subsequent bytes or if the segments are invalid.
+func (ber BerTLV) BitString() (asn1.BitString, error) {
+	if ber.Tag.IsConstructed() {
+		segments, err := ber.segments(UniversalBitString)
+		if err != nil {
+			return asn1.BitString{}, err
+		}
+
+		bs := asn1.BitString{Bytes: []byte{}}
+
+		for i, segment := range segments {
+			s, err := segment.BitString()
+			if err != nil {
+				return asn1.BitString{}, err
+			}
+
+			if i < len(segments)-1 && s.BitLength%8 != 0 {
+				return asn1.BitString{}, errors.Errorf("tag %02X: segment %d has unused bits but is not the last segment", []byte(ber.Tag), i)
+			}
+
+			bs.Bytes = append(bs.Bytes, s.Bytes...)
+			bs.BitLength += s.BitLength
+		}
+
+		return bs, nil
+	}
+
+	if len(ber.Value) == 0 {
+		return asn1.BitString{}, errors.Errorf("tag %02X: bit string value is empty", []byte(ber.Tag))
+	}
+
+	unused := int(ber.Value[0])
+	if unused > 7 || (unused > 0 && len(ber.Value) == 1) {
+		return asn1.BitString{}, errors.Errorf("tag %02X: invalid number of unused bits: %d", []byte(ber.Tag), unused)
+	}
+
+	return asn1.BitString{Bytes: ber.Value[1:], BitLength: 8*(len(ber.Value)-1) - unused}, nil
+}
+
+// OctetString returns the value of the BerTLV as ASN.1 OCTET STRING.
+// In primitive form, the value of the BerTLV is returned. In constructed form, which BER allows for long strings,
+// the values of the primitive segments are concatenated into a new slice. The segments must have the universal
+// OCTET STRING tag and may be constructed themselves. The tag of the BerTLV itself is not checked, so implicitly
+// tagged strings are supported. Returns an error if a segment has another tag or is malformed.
+func (ber BerTLV) OctetString() ([]byte, error) {
+	if !ber.Tag.IsConstructed() {
+		return ber.Value, nil
+	}
+
+	segments, err := ber.segments(UniversalOctetString)
+	if err != nil {
+		return nil, err
+	}
+
+	b := []byte{}
+	for _, segment := range segments {
+		b = append(b, segment.Value...)
+	}
+
+	return b, nil
+}
+
+// segments returns the primitive segments of a string in constructed form in the order they are encoded.
+// The segments must have the universal tag with the given number, constructed segments are resolved recursively.
+func (ber BerTLV) segments(number uint64) ([]BerTLV, error) {
+	children := ber.children
+
+	// the value was not parsed as constructed, e.g. because of ParseOptions.Form
+	if children == nil && len(ber.Value) > 0 {
+		var err error
+
+		p := parser{}
+		if children, _, err = p.parse(ber.Value, 0, false); err != nil {
+			return nil, errors.Wrapf(err, "tag %02X: invalid segments", []byte(ber.Tag))
+		}
+	}
+
+	var segments []BerTLV
+
+	for _, child := range children {
+		if child.unparsed != nil {
+			return nil, errors.Wrapf(child.unparsed, "tag %02X: invalid segments", []byte(ber.Tag))
+		}
+
+		if child.Tag.Class() != Universal || child.Tag.Number() != number {
+			return nil, errors.Errorf("tag %02X: segment has tag %02X, expected universal tag number %d",
+				[]byte(ber.Tag), []byte(child.Tag), number)
+		}
+
+		if !child.Tag.IsConstructed() {
+			segments = append(segments, child)
+			continue
+		}
+
+		nested, err := child.segments(number)
+		if err != nil {
+			return nil, err
+		}
+
+		segments = append(segments, nested...)
+	}
+
+	return segments, nil
+}
+
+// UTF8String returns the value of the BerTLV decoded as ASN.1 UTF8String.
+// Returns an error if the value is not valid UTF-8.
+func (ber BerTLV) UTF8String() (string, error) {
+	return ber.string(checkUTF8String)
+}
+
+// PrintableString returns the value of the BerTLV decoded as ASN.1 PrintableString.
+// Returns an error if the value contains characters that are not allowed in a PrintableString.
+func (ber BerTLV) PrintableString() (string, error) {
+	return ber.string(checkPrintableString)
+}
+
+// IA5String returns the value of the BerTLV decoded as ASN.1 IA5String.
+// Returns an error if the value contains characters that are not ASCII.
+func (ber BerTLV) IA5String() (string, error) {
+	return ber.string(checkIA5String)
+}
+
+func (ber BerTLV) string(check func(b []byte) error) (string, error) {
+	if err := check(ber.Value); err != nil {
+		return "", errors.Wrapf(err, "tag %02X", []byte(ber.Tag))
+	}
+
+	return string(ber.Value), nil
+}
+
+// BMPString returns the value of the BerTLV decoded as ASN.1 BMPString (UCS-2 big-endian).
+// Returns an error if the value has an odd length or contains UTF-16 surrogates.
+func (ber BerTLV) BMPString() (string, error) {
+	if len(ber.Value)%2 != 0 {
+		return "", errors.Errorf("tag %02X: BMPString has odd length %d", []byte(ber.Tag), len(ber.Value))
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(ber.Value) / 2)
+
+	for i := 0; i < len(ber.Value); i += 2 {
+		r := rune(ber.Value[i])<<8 | rune(ber.Value[i+1])
+		if utf16.IsSurrogate(r) {
+			return "", errors.Errorf("tag %02X: invalid BMPString character at byte %d", []byte(ber.Tag), i)
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String(), nil
+}
+
+// UTCTime returns the value of the BerTLV decoded as ASN.1 UTCTime in one of the formats YYMMDDhhmm[ss]Z or
+// YYMMDDhhmm[ss]+hhmm. Years 50 - 99 are interpreted as 1950 - 1999, years 00 - 49 as 2000 - 2049.
+// Returns an error if the value is not a valid UTCTime.
+func (ber BerTLV) UTCTime() (time.Time, error) {
+	s := string(ber.Value)
+
+	var layout string
+
+	// the seconds are optional, the time difference is mandatory
+	switch strings.IndexAny(s, "Z+-") {
+	case 10:
+		layout = "0601021504Z0700"
+	case 12:
+		layout = "060102150405Z0700"
+	default:
+		return time.Time{}, errors.Errorf("tag %02X: invalid UTCTime %q", []byte(ber.Tag), s)
+	}
+
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "tag %02X: invalid UTCTime %q", []byte(ber.Tag), s)
+	}
+
+	// time.Parse maps the years 69 - 99 to the 20th century
+	if t.Year() >= 2050 {
+		t = t.AddDate(-100, 0, 0)
+	}
+
+	return t, nil
+}
+
+// GeneralizedTime returns the value of the BerTLV decoded as ASN.1 GeneralizedTime in the format
+// YYYYMMDDhh[mm[ss[.f]]] followed by 'Z', a time difference +hh[mm] or nothing for local time, which is interpreted
+// in time.Local. The decimal mark of fractional seconds may be '.' or ','. Fractions of hours or minutes are not
+// supported. Returns an error if the value is not a valid GeneralizedTime.
+func (ber BerTLV) GeneralizedTime() (time.Time, error) {
+	s := strings.Replace(string(ber.Value), ",", ".", 1)
+
+	invalid := errors.Errorf("tag %02X: invalid GeneralizedTime %q", []byte(ber.Tag), string(ber.Value))
+
+	end := len(s)
+	zoneLayout := ""
+
+	if i := strings.IndexAny(s, "Z+-"); i >= 0 {
+		switch len(s) - i {
+		case 1, 5:
+			zoneLayout = "Z0700"
+		case 3:
+			zoneLayout = "Z07"
+		default:
+			return time.Time{}, invalid
+		}
+
+		end = i
+	}
+
+	var layout string
+
+	if i := strings.IndexByte(s[:end], '.'); i >= 0 {
+		digits := end - i - 1
+		if i != 14 || digits < 1 || digits > 9 {
+			return time.Time{}, invalid
+		}
+
+		layout = "20060102150405." + strings.Repeat("0", digits)
+	} else {
+		switch end {
+		case 10:
+			layout = "2006010215"
+		case 12:
+			layout = "200601021504"
+		case 14:
+			layout = "20060102150405"
+		default:
+			return time.Time{}, invalid
+		}
+	}
+
+	t, err := time.ParseInLocation(layout+zoneLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "tag %02X: invalid GeneralizedTime %q", []byte(ber.Tag), string(ber.Value))
+	}
+
+	return t, nil
+}
+
+// Real returns the value of the BerTLV decoded as ASN.1 REAL in binary, decimal (ISO 6093 NR1, NR2 or NR3)
+// or special encoding. Values that can not be represented exactly are rounded to the nearest float64.
+// Returns an error if the encoding is invalid.
+func (ber BerTLV) Real() (float64, error) {
+	v := ber.Value
+	if len(v) == 0 {
+		return 0, nil
+	}
+
+	switch first := v[0]; {
+	case first&0x80 != 0:
+		f, _, err := decodeBinaryReal(v)
+		if err != nil {
+			return 0, errors.Wrapf(err, "tag %02X", []byte(ber.Tag))
+		}
+
+		return f, nil
+	case first&0x40 != 0:
+		if len(v) != 1 {
+			return 0, errors.Errorf("tag %02X: special real value must have a length of 1, got %d", []byte(ber.Tag), len(v))
+		}
+
+		switch first {
+		case 0x40:
+			return math.Inf(1), nil
+		case 0x41:
+			return math.Inf(-1), nil
+		case 0x42:
+			return math.NaN(), nil
+		case 0x43:
+			return math.Copysign(0, -1), nil
+		default:
+			return 0, errors.Errorf("tag %02X: invalid special real value %02X", []byte(ber.Tag), first)
+		}
+	default:
+		if first < 0x01 || first > 0x03 {
+			return 0, errors.Errorf("tag %02X: invalid decimal real form %02X", []byte(ber.Tag), first)
+		}
+
+		s := strings.TrimSpace(string(v[1:]))
+		if strings.Trim(s, "0123456789+-.,Ee") != "" {
+			return 0, errors.Errorf("tag %02X: invalid decimal real %q", []byte(ber.Tag), string(v[1:]))
+		}
+
+		f, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "tag %02X: invalid decimal real %q", []byte(ber.Tag), string(v[1:]))
+		}
+
+		return f, nil
+	}
+}
+
+func encodeInteger(v *big.Int) []byte {
+	switch v.Sign() {
+	case 0:
+		return []byte{0x00}
+	case 1:
+		// one more bit for the sign
+		return v.FillBytes(make([]byte, v.BitLen()/8+1))
+	default:
+		// two's complement of v is the inverted magnitude of -v - 1
+		m := new(big.Int).Neg(v)
+		m.Sub(m, big.NewInt(1))
+
+		b := m.FillBytes(make([]byte, m.BitLen()/8+1))
+		for i := range b {
+			b[i] = ^b[i]
+		}
+
+		// the magnitude may be sensitive as well
+		words := m.Bits()
+		for i := range words {
+			words[i] = 0
+		}
+
+		return b
+	}
+}
+
+// encodeBitString returns the contents bytes of bs, i.e. the number of unused bits followed by the bytes of bs
+// with the unused bits set to zero.
+func encodeBitString(bs asn1.BitString) ([]byte, error) {
+	if bs.BitLength < 0 || (bs.BitLength+7)/8 != len(bs.Bytes) {
+		return nil, errors.Errorf("bit length %d does not match %d bytes", bs.BitLength, len(bs.Bytes))
+	}
+
+	unused := len(bs.Bytes)*8 - bs.BitLength
+
+	b := make([]byte, 1, len(bs.Bytes)+1)
+	b[0] = byte(unused)
+	b = append(b, bs.Bytes...)
+
+	if unused > 0 {
+		b[len(b)-1] &= 0xFF << uint(unused)
+	}
+
+	return b, nil
+}
+
+func encodeObjectIdentifier(oid asn1.ObjectIdentifier) ([]byte, error) {
+	if len(oid) < 2 {
+		return nil, errors.New("object identifier must have at least two arcs")
+	}
+
+	if oid[0] < 0 || oid[0] > 2 || oid[1] < 0 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, errors.Errorf("invalid first arcs of object identifier: %d.%d", oid[0], oid[1])
+	}
+
+	if oid[1] > maxSubidentifier-80 {
+		return nil, errors.Errorf("second arc of object identifier is too large: %d", oid[1])
+	}
+
+	arcs := make([]int, len(oid)-1)
+	arcs[0] = 40*oid[0] + oid[1]
+	copy(arcs[1:], oid[2:])
+
+	return appendSubidentifiers(nil, arcs)
+}
+
+func appendSubidentifiers(b []byte, arcs []int) ([]byte, error) {
+	for i, arc := range arcs {
+		if arc < 0 {
+			return nil, errors.Errorf("arc %d is negative: %d", i, arc)
+		}
+
+		if arc > maxSubidentifier {
+			return nil, errors.Errorf("arc %d is too large: %d", i, arc)
+		}
+
+		// 7 bits per byte, b8 indicates that another byte follows
+		n := 1
+		for rest := arc >> 7; rest > 0; rest >>= 7 {
+			n++
+		}
+
+		for j := n - 1; j >= 0; j-- {
+			s := byte(arc>>(7*uint(j))) & 0x7F
+			if j > 0 {
+				s |= 0x80
+			}
+
+			b = append(b, s)
+		}
+	}
+
+	return b, nil
+}
+
+func checkUTF8String(b []byte) error {
+	if !utf8.Valid(b) {
+		return errors.New("invalid UTF-8")
+	}
+
+	return nil
+}
+
+func checkPrintableString(b []byte) error {
+	for i, c := range b {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case strings.IndexByte(" '()+,-./:=?", c) >= 0:
+		default:
+			return errors.Errorf("character at index %d is not allowed in a PrintableString: %q", i, c)
+		}
+	}
+
+	return nil
+}
+
+func checkIA5String(b []byte) error {
+	for i, c := range b {
+		if c > 0x7F {
+			return errors.Errorf("character at index %d is not allowed in an IA5String: %02X", i, c)
+		}
+	}
+
+	return nil
+}
+
+func encodeReal(v float64) []byte {
+	switch {
+	case math.IsInf(v, 1):
+		return []byte{0x40}
+	case math.IsInf(v, -1):
+		return []byte{0x41}
+	case math.IsNaN(v):
+		return []byte{0x42}
+	case v == 0 && math.Signbit(v):
+		return []byte{0x43}
+	case v == 0:
+		return nil
+	}
+
+	first := byte(0x80)
+	if v < 0 {
+		first |= 0x40
+	}
+
+	// v = frac * 2^exp with frac in [0.5, 1): the mantissa is the integer of the 53 significant bits
+	frac, exp := math.Frexp(math.Abs(v))
+	mantissa := uint64(frac * (1 << 53))
+	exp -= 53
+
+	for mantissa&1 == 0 {
+		mantissa >>= 1
+		exp++
+	}
+
+	e, _ := encodeInt(int64(exp), 0)
+	m, _ := encodeUint(mantissa, 0)
+
+	// exponent format: length of the exponent minus one, at most two bytes for float64
+	first |= byte(len(e) - 1)
+
+	b := make([]byte, 0, 1+len(e)+len(m))
+	b = append(b, first)
+	b = append(b, e...)
+	b = append(b, m...)
+
+	wipe(e)
+	wipe(m)
+
+	return b
+}
+
+// decodeBinaryReal decodes a REAL in binary encoding and returns whether the float64 is exact or rounded.
+func decodeBinaryReal(v []byte) (float64, big.Accuracy, error) {
+	first := v[0]
+	rest := v[1:]
+
+	var shift int
+
+	switch (first >> 4) & 0x03 {
+	case 0:
+		shift = 1
+	case 1:
+		shift = 3
+	case 2:
+		shift = 4
+	default:
+		return 0, 0, errors.New("reserved base of binary real")
+	}
+
+	l := int(first&0x03) + 1
+	if l == 4 {
+		if len(rest) == 0 {
+			return 0, 0, errors.New("binary real is truncated")
+		}
+
+		l = int(rest[0])
+		rest = rest[1:]
+	}
+
+	if l == 0 || l > 4 {
+		return 0, 0, errors.Errorf("unsupported exponent length of binary real: %d", l)
+	}
+
+	if len(rest) <= l {
+		return 0, 0, errors.New("binary real is truncated")
+	}
+
+	exp := int64(int8(rest[0]))
+	for _, b := range rest[1:l] {
+		exp = exp<<8 | int64(b)
+	}
+
+	scale := int64((first >> 2) & 0x03)
+
+	// value = mantissa * 2^scale * base^exp
+	f := new(big.Float).SetInt(new(big.Int).SetBytes(rest[l:]))
+	f.SetMantExp(f, int(exp*int64(shift)+scale))
+
+	r, accuracy := f.Float64()
+	if first&0x40 != 0 {
+		r = -r
+	}
+
+	return r, accuracy, nil
+}
@@ -0,0 +1,752 @@
+package bertlv
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuilder_AddUniversal(t *testing.T) {
+	tag := NewOneByteTag(0x80)
+
+	tests := []struct {
+		name        string
+		build       func(bu *Builder)
+		expected    []byte
+		expectError bool
+	}{
+		{
+			name:     "integer, zero",
+			build:    func(bu *Builder) { bu.AddInteger(tag, big.NewInt(0)) },
+			expected: []byte{0x80, 0x01, 0x00},
+		},
+		{
+			name:     "integer, positive with leading zero",
+			build:    func(bu *Builder) { bu.AddInteger(tag, big.NewInt(128)) },
+			expected: []byte{0x80, 0x02, 0x00, 0x80},
+		},
+		{
+			name:     "integer, -128",
+			build:    func(bu *Builder) { bu.AddInteger(tag, big.NewInt(-128)) },
+			expected: []byte{0x80, 0x01, 0x80},
+		},
+		{
+			name:     "integer, -129",
+			build:    func(bu *Builder) { bu.AddInteger(tag, big.NewInt(-129)) },
+			expected: []byte{0x80, 0x02, 0xFF, 0x7F},
+		},
+		{
+			name:     "integer, -1",
+			build:    func(bu *Builder) { bu.AddInteger(tag, big.NewInt(-1)) },
+			expected: []byte{0x80, 0x01, 0xFF},
+		},
+		{
+			name:     "integer, 2^64",
+			build:    func(bu *Builder) { bu.AddInteger(tag, new(big.Int).Lsh(big.NewInt(1), 64)) },
+			expected: []byte{0x80, 0x09, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name:     "enumerated",
+			build:    func(bu *Builder) { bu.AddEnumerated(tag, 255) },
+			expected: []byte{0x80, 0x02, 0x00, 0xFF},
+		},
+		{
+			name:     "null",
+			build:    func(bu *Builder) { bu.AddNull(NewOneByteTag(0x05)) },
+			expected: []byte{0x05, 0x00},
+		},
+		{
+			name: "object identifier",
+			build: func(bu *Builder) {
+				bu.AddObjectIdentifier(NewOneByteTag(0x06), asn1.ObjectIdentifier{1, 2, 840, 113549})
+			},
+			expected: []byte{0x06, 0x06, 0x2A, 0x86, 0x48, 0x86, 0xF7, 0x0D},
+		},
+		{
+			name:     "object identifier, joint-iso-itu-t",
+			build:    func(bu *Builder) { bu.AddObjectIdentifier(tag, asn1.ObjectIdentifier{2, 999, 3}) },
+			expected: []byte{0x80, 0x03, 0x88, 0x37, 0x03},
+		},
+		{
+			name:        "Error: object identifier, one arc",
+			build:       func(bu *Builder) { bu.AddObjectIdentifier(tag, asn1.ObjectIdentifier{1}) },
+			expectError: true,
+		},
+		{
+			name:        "Error: object identifier, invalid second arc",
+			build:       func(bu *Builder) { bu.AddObjectIdentifier(tag, asn1.ObjectIdentifier{1, 40}) },
+			expectError: true,
+		},
+		{
+			name:        "Error: object identifier, negative arc",
+			build:       func(bu *Builder) { bu.AddObjectIdentifier(tag, asn1.ObjectIdentifier{1, 2, -1}) },
+			expectError: true,
+		},
+		{
+			name:     "object identifier, largest arc",
+			build:    func(bu *Builder) { bu.AddObjectIdentifier(tag, asn1.ObjectIdentifier{1, 2, math.MaxInt32}) },
+			expected: []byte{0x80, 0x06, 0x2A, 0x87, 0xFF, 0xFF, 0xFF, 0x7F},
+		},
+		{
+			name:        "Error: object identifier, second arc too large",
+			build:       func(bu *Builder) { bu.AddObjectIdentifier(tag, asn1.ObjectIdentifier{2, math.MaxInt32 - 79}) },
+			expectError: true,
+		},
+		{
+			name:     "relative oid",
+			build:    func(bu *Builder) { bu.AddRelativeOID(tag, []int{8571, 3, 2}) },
+			expected: []byte{0x80, 0x04, 0xC2, 0x7B, 0x03, 0x02},
+		},
+		{
+			name:        "Error: relative oid, empty",
+			build:       func(bu *Builder) { bu.AddRelativeOID(tag, nil) },
+			expectError: true,
+		},
+		{
+			name: "Error: relative oid, arc too large",
+			build: func(bu *Builder) {
+				arc := math.MaxInt32
+				arc++
+				bu.AddRelativeOID(tag, []int{arc})
+			},
+			expectError: true,
+		},
+		{
+			name:     "bit string, unused bits are cleared",
+			build:    func(bu *Builder) { bu.AddBitString(tag, asn1.BitString{Bytes: []byte{0x0A, 0x3F}, BitLength: 12}) },
+			expected: []byte{0x80, 0x03, 0x04, 0x0A, 0x30},
+		},
+		{
+			name:     "bit string, empty",
+			build:    func(bu *Builder) { bu.AddBitString(tag, asn1.BitString{}) },
+			expected: []byte{0x80, 0x01, 0x00},
+		},
+		{
+			name:        "Error: bit string, length mismatch",
+			build:       func(bu *Builder) { bu.AddBitString(tag, asn1.BitString{Bytes: []byte{0x0A, 0x3F}, BitLength: 17}) },
+			expectError: true,
+		},
+		{
+			name:     "octet string",
+			build:    func(bu *Builder) { bu.AddOctetString(tag, []byte{0x01, 0x02}) },
+			expected: []byte{0x80, 0x02, 0x01, 0x02},
+		},
+		{
+			name:     "utf8 string",
+			build:    func(bu *Builder) { bu.AddUTF8String(tag, "Ä") },
+			expected: []byte{0x80, 0x02, 0xC3, 0x84},
+		},
+		{
+			name:        "Error: utf8 string, invalid",
+			build:       func(bu *Builder) { bu.AddUTF8String(tag, "\xC3") },
+			expectError: true,
+		},
+		{
+			name:     "printable string",
+			build:    func(bu *Builder) { bu.AddPrintableString(tag, "Test 1") },
+			expected: []byte{0x80, 0x06, 'T', 'e', 's', 't', ' ', '1'},
+		},
+		{
+			name:        "Error: printable string, invalid character",
+			build:       func(bu *Builder) { bu.AddPrintableString(tag, "a@b") },
+			expectError: true,
+		},
+		{
+			name:     "ia5 string",
+			build:    func(bu *Builder) { bu.AddIA5String(tag, "a@b") },
+			expected: []byte{0x80, 0x03, 'a', '@', 'b'},
+		},
+		{
+			name:        "Error: ia5 string, not ascii",
+			build:       func(bu *Builder) { bu.AddIA5String(tag, "Ä") },
+			expectError: true,
+		},
+		{
+			name:     "bmp string",
+			build:    func(bu *Builder) { bu.AddBMPString(tag, "aÄ€") },
+			expected: []byte{0x80, 0x06, 0x00, 0x61, 0x00, 0xC4, 0x20, 0xAC},
+		},
+		{
+			name:        "Error: bmp string, outside of basic multilingual plane",
+			build:       func(bu *Builder) { bu.AddBMPString(tag, "😀") },
+			expectError: true,
+		},
+		{
+			name: "utc time",
+			build: func(bu *Builder) {
+				bu.AddUTCTime(tag, time.Date(2019, time.March, 4, 13, 14, 15, 0, time.FixedZone("", 3600)))
+			},
+			expected: append([]byte{0x80, 0x0D}, "190304121415Z"...),
+		},
+		{
+			name:        "Error: utc time, year out of range",
+			build:       func(bu *Builder) { bu.AddUTCTime(tag, time.Date(2050, time.January, 1, 0, 0, 0, 0, time.UTC)) },
+			expectError: true,
+		},
+		{
+			name:     "generalized time",
+			build:    func(bu *Builder) { bu.AddGeneralizedTime(tag, time.Date(2050, time.January, 1, 0, 0, 0, 0, time.UTC)) },
+			expected: append([]byte{0x80, 0x0F}, "20500101000000Z"...),
+		},
+		{
+			name: "generalized time, fractional seconds",
+			build: func(bu *Builder) {
+				bu.AddGeneralizedTime(tag, time.Date(2050, time.January, 1, 0, 0, 0, 120000000, time.UTC))
+			},
+			expected: append([]byte{0x80, 0x12}, "20500101000000.12Z"...),
+		},
+		{
+			name:     "real, zero",
+			build:    func(bu *Builder) { bu.AddReal(tag, 0) },
+			expected: []byte{0x80, 0x00},
+		},
+		{
+			name:     "real, one",
+			build:    func(bu *Builder) { bu.AddReal(tag, 1) },
+			expected: []byte{0x80, 0x03, 0x80, 0x00, 0x01},
+		},
+		{
+			name:     "real, -0.75",
+			build:    func(bu *Builder) { bu.AddReal(tag, -0.75) },
+			expected: []byte{0x80, 0x03, 0xC0, 0xFE, 0x03},
+		},
+		{
+			name:     "real, 2^200",
+			build:    func(bu *Builder) { bu.AddReal(tag, math.Ldexp(1, 200)) },
+			expected: []byte{0x80, 0x04, 0x81, 0x00, 0xC8, 0x01},
+		},
+		{
+			name:     "real, negative infinity",
+			build:    func(bu *Builder) { bu.AddReal(tag, math.Inf(-1)) },
+			expected: []byte{0x80, 0x01, 0x41},
+		},
+		{
+			name:     "real, negative zero",
+			build:    func(bu *Builder) { bu.AddReal(tag, math.Copysign(0, -1)) },
+			expected: []byte{0x80, 0x01, 0x43},
+		},
+		{
+			name:        "Error: invalid tag",
+			build:       func(bu *Builder) { bu.AddNull(NewOneByteTag(0x1F)) },
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bu := &Builder{}
+			tc.build(bu)
+
+			err := bu.Err()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if err == nil && !bytes.Equal(bu.Bytes(), tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, bu.Bytes())
+			}
+		})
+	}
+}
+
+func TestBerTLV_Integer(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    string
+		expectError bool
+	}{
+		{name: "zero", value: []byte{0x00}, expected: "0"},
+		{name: "128", value: []byte{0x00, 0x80}, expected: "128"},
+		{name: "-128", value: []byte{0x80}, expected: "-128"},
+		{name: "-129", value: []byte{0xFF, 0x7F}, expected: "-129"},
+		{name: "-2^64", value: []byte{0xFF, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, expected: "-18446744073709551616"},
+		{name: "Error: empty", value: nil, expectError: true},
+		{name: "Error: leading zero", value: []byte{0x00, 0x7F}, expectError: true},
+		{name: "Error: leading ones", value: []byte{0xFF, 0x80}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewOneByteTag(0x02), Value: tc.value}.Integer()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if err == nil && received.String() != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_Enumerated(t *testing.T) {
+	received, err := BerTLV{Tag: NewOneByteTag(0x0A), Value: []byte{0xFF, 0x7F}}.Enumerated()
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	if received != -129 {
+		t.Errorf("Expected: '%v', got: '%v'", -129, received)
+	}
+
+	if _, err = (BerTLV{Tag: NewOneByteTag(0x0A), Value: []byte{0x00, 0x01}}).Enumerated(); err == nil {
+		t.Errorf("Expected: error, got: nil")
+	}
+}
+
+func TestBerTLV_Null(t *testing.T) {
+	if err := (BerTLV{Tag: NewOneByteTag(0x05)}).Null(); err != nil {
+		t.Errorf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	if err := (BerTLV{Tag: NewOneByteTag(0x05), Value: []byte{0x00}}).Null(); err == nil {
+		t.Errorf("Expected: error, got: nil")
+	}
+}
+
+func TestBerTLV_ObjectIdentifier(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    asn1.ObjectIdentifier
+		expectError bool
+	}{
+		{name: "rsadsi", value: []byte{0x2A, 0x86, 0x48, 0x86, 0xF7, 0x0D}, expected: asn1.ObjectIdentifier{1, 2, 840, 113549}},
+		{name: "itu-t", value: []byte{0x00}, expected: asn1.ObjectIdentifier{0, 0}},
+		{name: "joint-iso-itu-t", value: []byte{0x88, 0x37, 0x03}, expected: asn1.ObjectIdentifier{2, 999, 3}},
+		{name: "Error: empty", value: nil, expectError: true},
+		{name: "Error: not minimal", value: []byte{0x2A, 0x80, 0x01}, expectError: true},
+		{name: "Error: truncated", value: []byte{0x2A, 0x86}, expectError: true},
+		{name: "largest subidentifier", value: []byte{0x2A, 0x87, 0xFF, 0xFF, 0xFF, 0x7F}, expected: asn1.ObjectIdentifier{1, 2, math.MaxInt32}},
+		{name: "Error: subidentifier too large", value: []byte{0x2A, 0x88, 0x80, 0x80, 0x80, 0x00}, expectError: true},
+		{name: "Error: overflow", value: []byte{0x2A, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewOneByteTag(0x06), Value: tc.value}.ObjectIdentifier()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if !received.Equal(tc.expected) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_RelativeOID(t *testing.T) {
+	received, err := BerTLV{Tag: NewOneByteTag(0x0D), Value: []byte{0xC2, 0x7B, 0x03, 0x02}}.RelativeOID()
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	if diff := cmp.Diff([]int{8571, 3, 2}, received); diff != "" {
+		t.Errorf("Mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBerTLV_BitString(t *testing.T) {
+	tests := []struct {
+		name        string
+		tag         BerTag
+		value       []byte
+		expected    asn1.BitString
+		expectError bool
+	}{
+		{name: "unused bits", tag: NewOneByteTag(0x03), value: []byte{0x04, 0x0A, 0x30}, expected: asn1.BitString{Bytes: []byte{0x0A, 0x30}, BitLength: 12}},
+		{name: "empty", tag: NewOneByteTag(0x03), value: []byte{0x00}, expected: asn1.BitString{Bytes: []byte{}}},
+		{name: "Error: empty value", tag: NewOneByteTag(0x03), value: nil, expectError: true},
+		{name: "Error: unused bits without bytes", tag: NewOneByteTag(0x03), value: []byte{0x01}, expectError: true},
+		{name: "Error: too many unused bits", tag: NewOneByteTag(0x03), value: []byte{0x08, 0x00}, expectError: true},
+		{name: "constructed", tag: NewOneByteTag(0x23), value: []byte{0x03, 0x02, 0x00, 0x0A, 0x03, 0x02, 0x04, 0x30}, expected: asn1.BitString{Bytes: []byte{0x0A, 0x30}, BitLength: 12}},
+		{name: "constructed, nested", tag: NewOneByteTag(0x23), value: []byte{0x23, 0x04, 0x03, 0x02, 0x00, 0x0A, 0x03, 0x02, 0x04, 0x30}, expected: asn1.BitString{Bytes: []byte{0x0A, 0x30}, BitLength: 12}},
+		{name: "constructed, empty", tag: NewOneByteTag(0x23), value: nil, expected: asn1.BitString{Bytes: []byte{}}},
+		{name: "Error: constructed, unused bits in first segment", tag: NewOneByteTag(0x23), value: []byte{0x03, 0x02, 0x04, 0x0A, 0x03, 0x02, 0x00, 0x30}, expectError: true},
+		{name: "Error: constructed, invalid segment tag", tag: NewOneByteTag(0x23), value: []byte{0x04, 0x02, 0x00, 0x0A}, expectError: true},
+		{name: "Error: constructed, invalid segment", tag: NewOneByteTag(0x23), value: []byte{0x03, 0x01, 0x08}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: tc.tag, Value: tc.value}.BitString()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if diff := cmp.Diff(tc.expected, received); diff != "" {
+				t.Errorf("Mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBerTLV_OctetString(t *testing.T) {
+	tests := []struct {
+		name        string
+		tag         BerTag
+		value       []byte
+		expected    []byte
+		expectError bool
+	}{
+		{name: "primitive", tag: NewOneByteTag(0x04), value: []byte{0x01, 0x02}, expected: []byte{0x01, 0x02}},
+		{name: "constructed", tag: NewOneByteTag(0x24), value: []byte{0x04, 0x02, 0x01, 0x02, 0x04, 0x01, 0x03}, expected: []byte{0x01, 0x02, 0x03}},
+		{name: "constructed, nested", tag: NewOneByteTag(0x24), value: []byte{0x04, 0x02, 0x01, 0x02, 0x24, 0x04, 0x04, 0x02, 0x03, 0x04}, expected: []byte{0x01, 0x02, 0x03, 0x04}},
+		{name: "constructed, implicit tag", tag: NewOneByteTag(0xA0), value: []byte{0x04, 0x01, 0x01, 0x04, 0x00}, expected: []byte{0x01}},
+		{name: "constructed, empty", tag: NewOneByteTag(0x24), value: nil, expected: []byte{}},
+		{name: "Error: constructed, invalid segment tag", tag: NewOneByteTag(0x24), value: []byte{0x04, 0x01, 0x01, 0x80, 0x01, 0x02}, expectError: true},
+		{name: "Error: constructed, application segment tag", tag: NewOneByteTag(0x24), value: []byte{0x44, 0x01, 0x01}, expectError: true},
+		{name: "Error: constructed, malformed", tag: NewOneByteTag(0x24), value: []byte{0x04, 0x05, 0x01}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: tc.tag, Value: tc.value}.OctetString()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if !bytes.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_OctetString_Parsed(t *testing.T) {
+	input := []byte{0x30, 0x0E, 0x24, 0x0C, 0x04, 0x02, 0x01, 0x02, 0x24, 0x06, 0x04, 0x01, 0x03, 0x04, 0x01, 0x04}
+	expected := []byte{0x01, 0x02, 0x03, 0x04}
+
+	for _, form := range []Form{FormDefault, FormPrimitive} {
+		form := form
+
+		tlvs, err := ParseWithOptions(input, ParseOptions{Form: func(tag BerTag) Form {
+			if tag[0] == 0x24 {
+				return form
+			}
+
+			return FormDefault
+		}})
+		if err != nil {
+			t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+		}
+
+		received, err := tlvs[0].FirstChild(nil).OctetString()
+		if err != nil {
+			t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+		}
+
+		if !bytes.Equal(received, expected) {
+			t.Errorf("Expected: '%X', got: '%X'", expected, received)
+		}
+	}
+}
+
+func TestBuilder_SegmentStrings(t *testing.T) {
+	long := make([]byte, 2500)
+	for i := range long {
+		long[i] = byte(i)
+	}
+
+	tests := []struct {
+		name        string
+		build       func(bu *Builder)
+		expected    []byte
+		expectError bool
+	}{
+		{
+			name:     "octet string, not segmented",
+			build:    func(bu *Builder) { bu.SegmentStrings(3).AddOctetString(NewOneByteTag(0x04), []byte{0x01, 0x02, 0x03}) },
+			expected: []byte{0x04, 0x03, 0x01, 0x02, 0x03},
+		},
+		{
+			name:     "octet string, segmented",
+			build:    func(bu *Builder) { bu.SegmentStrings(2).AddOctetString(NewOneByteTag(0x04), []byte{0x01, 0x02, 0x03}) },
+			expected: []byte{0x24, 0x07, 0x04, 0x02, 0x01, 0x02, 0x04, 0x01, 0x03},
+		},
+		{
+			name: "octet string, implicit tag",
+			build: func(bu *Builder) {
+				bu.SegmentStrings(2).AddOctetString(NewTwoByteTag(0x9F, 0x20), []byte{0x01, 0x02, 0x03})
+			},
+			expected: []byte{0xBF, 0x20, 0x07, 0x04, 0x02, 0x01, 0x02, 0x04, 0x01, 0x03},
+		},
+		{
+			name: "bit string, segmented",
+			build: func(bu *Builder) {
+				bu.SegmentStrings(2).AddBitString(NewOneByteTag(0x03), asn1.BitString{Bytes: []byte{0x0A, 0x3F}, BitLength: 12})
+			},
+			expected: []byte{0x23, 0x08, 0x03, 0x02, 0x00, 0x0A, 0x03, 0x02, 0x04, 0x30},
+		},
+		{
+			name: "disabled",
+			build: func(bu *Builder) {
+				bu.SegmentStrings(2).SegmentStrings(0).AddOctetString(NewOneByteTag(0x04), []byte{0x01, 0x02, 0x03})
+			},
+			expected: []byte{0x04, 0x03, 0x01, 0x02, 0x03},
+		},
+		{
+			name:        "Error: segment size 1",
+			build:       func(bu *Builder) { bu.SegmentStrings(1) },
+			expectError: true,
+		},
+		{
+			name:        "Error: invalid tag",
+			build:       func(bu *Builder) { bu.SegmentStrings(2).AddOctetString(NewOneByteTag(0x1F), []byte{0x01, 0x02, 0x03}) },
+			expectError: true,
+		},
+		{
+			name:        "Error: segments exceed 65535 bytes",
+			build:       func(bu *Builder) { bu.SegmentStrings(2).AddOctetString(NewOneByteTag(0x04), make([]byte, 40000)) },
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bu := &Builder{}
+			tc.build(bu)
+
+			err := bu.Err()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if !bytes.Equal(bu.Bytes(), tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, bu.Bytes())
+			}
+		})
+	}
+
+	t.Run("CER segments", func(t *testing.T) {
+		tlvs, err := (&Builder{}).
+			SegmentStrings(CERSegmentSize).
+			AddOctetString(NewOneByteTag(0x04), long).
+			AddBitString(NewOneByteTag(0x03), asn1.BitString{Bytes: long, BitLength: 8*len(long) - 1}).
+			BuildBerTLVs()
+		if err != nil {
+			t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+		}
+
+		for _, tlv := range tlvs {
+			segments := tlv.Children(nil)
+			if len(segments) != 3 {
+				t.Fatalf("Expected: 3 segments, got: %d", len(segments))
+			}
+
+			for _, segment := range segments[:2] {
+				if len(segment.Value) != CERSegmentSize {
+					t.Errorf("Expected: segment length %d, got: %d", CERSegmentSize, len(segment.Value))
+				}
+			}
+		}
+
+		octets, err := tlvs[0].OctetString()
+		if err != nil || !bytes.Equal(octets, long) {
+			t.Errorf("Expected: reassembled octet string, got: error(%v)", err)
+		}
+
+		bits, err := tlvs[1].BitString()
+		if err != nil || bits.BitLength != 8*len(long)-1 || !bytes.Equal(bits.Bytes[:len(long)-1], long[:len(long)-1]) {
+			t.Errorf("Expected: reassembled bit string, got: error(%v)", err)
+		}
+	})
+}
+
+func TestBerTLV_Strings(t *testing.T) {
+	tests := []struct {
+		name        string
+		decode      func(ber BerTLV) (string, error)
+		value       []byte
+		expected    string
+		expectError bool
+	}{
+		{name: "utf8", decode: BerTLV.UTF8String, value: []byte{0xC3, 0x84}, expected: "Ä"},
+		{name: "Error: utf8", decode: BerTLV.UTF8String, value: []byte{0xC3}, expectError: true},
+		{name: "printable", decode: BerTLV.PrintableString, value: []byte("(Test) 1=1?"), expected: "(Test) 1=1?"},
+		{name: "Error: printable", decode: BerTLV.PrintableString, value: []byte("a*b"), expectError: true},
+		{name: "ia5", decode: BerTLV.IA5String, value: []byte("a@b"), expected: "a@b"},
+		{name: "Error: ia5", decode: BerTLV.IA5String, value: []byte{0x61, 0x80}, expectError: true},
+		{name: "bmp", decode: BerTLV.BMPString, value: []byte{0x00, 0x61, 0x00, 0xC4, 0x20, 0xAC}, expected: "aÄ€"},
+		{name: "Error: bmp, odd length", decode: BerTLV.BMPString, value: []byte{0x00, 0x61, 0x00}, expectError: true},
+		{name: "Error: bmp, surrogate", decode: BerTLV.BMPString, value: []byte{0xD8, 0x3D, 0xDE, 0x00}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := tc.decode(BerTLV{Tag: NewOneByteTag(0x80), Value: tc.value})
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_Times(t *testing.T) {
+	tests := []struct {
+		name        string
+		decode      func(ber BerTLV) (time.Time, error)
+		value       string
+		expected    time.Time
+		expectError bool
+	}{
+		{name: "utc time", decode: BerTLV.UTCTime, value: "190304121415Z", expected: time.Date(2019, time.March, 4, 12, 14, 15, 0, time.UTC)},
+		{name: "utc time, 1950", decode: BerTLV.UTCTime, value: "500304121415Z", expected: time.Date(1950, time.March, 4, 12, 14, 15, 0, time.UTC)},
+		{name: "utc time, 2049", decode: BerTLV.UTCTime, value: "491231235959Z", expected: time.Date(2049, time.December, 31, 23, 59, 59, 0, time.UTC)},
+		{name: "utc time, without seconds", decode: BerTLV.UTCTime, value: "1903041214Z", expected: time.Date(2019, time.March, 4, 12, 14, 0, 0, time.UTC)},
+		{name: "utc time, time difference", decode: BerTLV.UTCTime, value: "190304131415+0100", expected: time.Date(2019, time.March, 4, 12, 14, 15, 0, time.UTC)},
+		{name: "Error: utc time, without time zone", decode: BerTLV.UTCTime, value: "190304121415", expectError: true},
+		{name: "Error: utc time, invalid month", decode: BerTLV.UTCTime, value: "191304121415Z", expectError: true},
+		{name: "generalized time", decode: BerTLV.GeneralizedTime, value: "20500101000000Z", expected: time.Date(2050, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "generalized time, hours only", decode: BerTLV.GeneralizedTime, value: "2050010112Z", expected: time.Date(2050, time.January, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "generalized time, fraction", decode: BerTLV.GeneralizedTime, value: "20500101000000.12Z", expected: time.Date(2050, time.January, 1, 0, 0, 0, 120000000, time.UTC)},
+		{name: "generalized time, comma", decode: BerTLV.GeneralizedTime, value: "20500101000000,5Z", expected: time.Date(2050, time.January, 1, 0, 0, 0, 500000000, time.UTC)},
+		{name: "generalized time, time difference hours", decode: BerTLV.GeneralizedTime, value: "205001010100-01", expected: time.Date(2050, time.January, 1, 2, 0, 0, 0, time.UTC)},
+		{name: "generalized time, local time", decode: BerTLV.GeneralizedTime, value: "20500101000000", expected: time.Date(2050, time.January, 1, 0, 0, 0, 0, time.Local)},
+		{name: "Error: generalized time, fraction of minutes", decode: BerTLV.GeneralizedTime, value: "205001010000.5Z", expectError: true},
+		{name: "Error: generalized time, empty fraction", decode: BerTLV.GeneralizedTime, value: "20500101000000.Z", expectError: true},
+		{name: "Error: generalized time, invalid time difference", decode: BerTLV.GeneralizedTime, value: "20500101000000+1", expectError: true},
+		{name: "Error: generalized time, invalid day", decode: BerTLV.GeneralizedTime, value: "20500231000000Z", expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := tc.decode(BerTLV{Tag: NewOneByteTag(0x80), Value: []byte(tc.value)})
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if !received.Equal(tc.expected) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_Real(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    float64
+		expectError bool
+	}{
+		{name: "zero", value: nil, expected: 0},
+		{name: "one", value: []byte{0x80, 0x00, 0x01}, expected: 1},
+		{name: "-0.75", value: []byte{0xC0, 0xFE, 0x03}, expected: -0.75},
+		{name: "base 8", value: []byte{0x90, 0x02, 0x03}, expected: 192},
+		{name: "base 16 with scale", value: []byte{0xA4, 0xFF, 0x01}, expected: 0.125},
+		{name: "exponent with length byte", value: []byte{0x83, 0x01, 0x02, 0x05}, expected: 20},
+		{name: "decimal NR1", value: append([]byte{0x01}, "  -12"...), expected: -12},
+		{name: "decimal NR2", value: append([]byte{0x02}, "1,5"...), expected: 1.5},
+		{name: "decimal NR3", value: append([]byte{0x03}, "15E-1"...), expected: 1.5},
+		{name: "overflow", value: []byte{0x81, 0x10, 0x00, 0x01}, expected: math.Inf(1)},
+		{name: "infinity", value: []byte{0x40}, expected: math.Inf(1)},
+		{name: "negative infinity", value: []byte{0x41}, expected: math.Inf(-1)},
+		{name: "Error: reserved base", value: []byte{0xB0, 0x00, 0x01}, expectError: true},
+		{name: "Error: truncated", value: []byte{0x81, 0x00}, expectError: true},
+		{name: "Error: invalid special value", value: []byte{0x44}, expectError: true},
+		{name: "Error: special value with content", value: []byte{0x40, 0x00}, expectError: true},
+		{name: "Error: invalid decimal form", value: append([]byte{0x04}, "1"...), expectError: true},
+		{name: "Error: invalid decimal", value: append([]byte{0x01}, "inf"...), expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewOneByteTag(0x09), Value: tc.value}.Real()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_Real_Special(t *testing.T) {
+	nan, err := BerTLV{Tag: NewOneByteTag(0x09), Value: []byte{0x42}}.Real()
+	if err != nil || !math.IsNaN(nan) {
+		t.Errorf("Expected: NaN, got: '%v', error(%v)", nan, err)
+	}
+
+	negativeZero, err := BerTLV{Tag: NewOneByteTag(0x09), Value: []byte{0x43}}.Real()
+	if err != nil || negativeZero != 0 || !math.Signbit(negativeZero) {
+		t.Errorf("Expected: -0, got: '%v', error(%v)", negativeZero, err)
+	}
+}
+
+func TestUniversal_RoundTrip(t *testing.T) {
+	reals := []float64{math.Pi, -1e-300, math.SmallestNonzeroFloat64, math.MaxFloat64, 0.1}
+	integers := []int64{0, 1, -1, 127, 128, -128, -129, math.MaxInt64, math.MinInt64}
+
+	bu := &Builder{}
+	for _, r := range reals {
+		bu.AddReal(NewOneByteTag(0x09), r)
+	}
+
+	for _, i := range integers {
+		bu.AddInteger(NewOneByteTag(0x02), big.NewInt(i))
+	}
+
+	tlvs, err := bu.BuildBerTLVs()
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	for i, r := range reals {
+		received, err := tlvs[i].Real()
+		if err != nil || received != r {
+			t.Errorf("Expected: '%v', got: '%v', error(%v)", r, received, err)
+		}
+	}
+
+	for i, v := range integers {
+		received, err := tlvs[len(reals)+i].Integer()
+		if err != nil || received.Int64() != v {
+			t.Errorf("Expected: '%v', got: '%v', error(%v)", v, received, err)
+		}
+	}
+}
+
+func TestUniversal_EncodingAsn1(t *testing.T) {
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 21, 20}
+	when := time.Date(2019, time.March, 4, 12, 14, 15, 0, time.UTC)
+
+	b := (&Builder{}).
+		AddObjectIdentifier(NewTag(Universal, false, UniversalObjectIdentifier), oid).
+		AddUTCTime(NewTag(Universal, false, UniversalUTCTime), when).
+		AddPrintableString(NewTag(Universal, false, UniversalPrintableString), "DE").
+		Bytes()
+
+	var receivedOID asn1.ObjectIdentifier
+
+	rest, err := asn1.Unmarshal(b, &receivedOID)
+	if err != nil || !receivedOID.Equal(oid) {
+		t.Fatalf("Expected: '%v', got: '%v', error(%v)", oid, receivedOID, err)
+	}
+
+	var receivedTime time.Time
+
+	rest, err = asn1.Unmarshal(rest, &receivedTime)
+	if err != nil || !receivedTime.Equal(when) {
+		t.Fatalf("Expected: '%v', got: '%v', error(%v)", when, receivedTime, err)
+	}
+
+	var receivedString string
+
+	if _, err = asn1.UnmarshalWithParams(rest, &receivedString, "printable"); err != nil || receivedString != "DE" {
+		t.Fatalf("Expected: 'DE', got: '%v', error(%v)", receivedString, err)
+	}
+}
@@ -0,0 +1,386 @@
+package bertlv
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AddUint adds the given tag with the unsigned integer v encoded big-endian to the Builder.
+// If width is 0, the minimal number of bytes is used (at least one), otherwise the value has exactly width bytes.
+// An error is recorded if v does not fit into width bytes.
+func (bu *Builder) AddUint(tag BerTag, v uint64, width int) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	b, err := encodeUint(v, width)
+	if err != nil {
+		return bu.fail("AddUint", err)
+	}
+
+	return bu.addTemporary("AddUint", tag, b)
+}
+
+// AddInt adds the given tag with the signed integer v encoded big-endian in two's complement to the Builder.
+// If width is 0, the minimal number of bytes is used (at least one), otherwise the value has exactly width bytes.
+// An error is recorded if v does not fit into width bytes.
+func (bu *Builder) AddInt(tag BerTag, v int64, width int) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	b, err := encodeInt(v, width)
+	if err != nil {
+		return bu.fail("AddInt", err)
+	}
+
+	return bu.addTemporary("AddInt", tag, b)
+}
+
+// AddString adds the given tag with the bytes of s to the Builder.
+func (bu *Builder) AddString(tag BerTag, s string) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	return bu.addTemporary("AddString", tag, []byte(s))
+}
+
+// AddBCD adds the given tag with the decimal digits encoded as BCD to the Builder, like the EMV format 'n'.
+// The digits are right-justified and padded with leading zeros to length bytes.
+// If length is 0, the minimal number of bytes is used.
+// An error is recorded if digits contains other characters than '0'-'9' or does not fit into length bytes.
+func (bu *Builder) AddBCD(tag BerTag, digits string, length int) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	b, err := encodeBCD(digits, length)
+	if err != nil {
+		return bu.fail("AddBCD", err)
+	}
+
+	return bu.addTemporary("AddBCD", tag, b)
+}
+
+// AddCompressedNumeric adds the given tag with the decimal digits encoded as compressed numeric to the Builder,
+// like the EMV format 'cn'. The digits are left-justified and padded with trailing 'F' nibbles to length bytes.
+// If length is 0, the minimal number of bytes is used.
+// An error is recorded if digits contains other characters than '0'-'9' or does not fit into length bytes.
+func (bu *Builder) AddCompressedNumeric(tag BerTag, digits string, length int) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	b, err := encodeCompressedNumeric(digits, length)
+	if err != nil {
+		return bu.fail("AddCompressedNumeric", err)
+	}
+
+	return bu.addTemporary("AddCompressedNumeric", tag, b)
+}
+
+// AddBool adds the given tag with a one byte boolean value to the Builder: 'FF' for true and '00' for false.
+func (bu *Builder) AddBool(tag BerTag, v bool) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	var b byte
+	if v {
+		b = 0xFF
+	}
+
+	return bu.addBytes("AddBool", tag, []byte{b})
+}
+
+// AddTLV adds the encoding of the given BerTLV to the Builder.
+// An error is recorded if its tag is not encoded correctly or its value exceeds a length of 65535.
+func (bu *Builder) AddTLV(tlv BerTLV) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	return bu.addTLV("AddTLV", tlv)
+}
+
+// AddTLVs adds the encoding of the given BerTLVs to the Builder. See AddTLV.
+func (bu *Builder) AddTLVs(tlvs BerTLVs) *Builder {
+	if !bu.next() {
+		return bu
+	}
+
+	for _, tlv := range tlvs {
+		if bu.addTLV("AddTLVs", tlv); bu.err != nil {
+			break
+		}
+	}
+
+	return bu
+}
+
+func (bu *Builder) addTLV(method string, tlv BerTLV) *Builder {
+	if tlv.unparsed != nil {
+		return bu.fail(method, errors.Wrap(tlv.unparsed, "unparsed bytes"))
+	}
+
+	return bu.addBytes(method, tlv.Tag, tlv.Value)
+}
+
+func encodeUint(v uint64, width int) ([]byte, error) {
+	n := 1
+	for rest := v >> 8; rest > 0; rest >>= 8 {
+		n++
+	}
+
+	if width == 0 {
+		width = n
+	}
+
+	if width < n {
+		return nil, errors.Errorf("%d does not fit into %d bytes", v, width)
+	}
+
+	b := make([]byte, width)
+	for i := width - 1; i >= 0 && v > 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+
+	return b, nil
+}
+
+func encodeInt(v int64, width int) ([]byte, error) {
+	// minimal number of bytes: the sign bit of the first byte must match the sign of v
+	n := 1
+	for rest := v >> 7; rest != 0 && rest != -1; rest >>= 8 {
+		n++
+	}
+
+	if width == 0 {
+		width = n
+	}
+
+	if width < n {
+		return nil, errors.Errorf("%d does not fit into %d bytes", v, width)
+	}
+
+	b := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+
+	return b, nil
+}
+
+func checkDigits(digits string) error {
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return errors.Errorf("invalid digit at index %d: %q", i, digits[i])
+		}
+	}
+
+	return nil
+}
+
+func encodeBCD(digits string, length int) ([]byte, error) {
+	if err := checkDigits(digits); err != nil {
+		return nil, err
+	}
+
+	if length == 0 {
+		length = (len(digits) + 1) / 2
+	}
+
+	if len(digits) > 2*length {
+		return nil, errors.Errorf("%d digits do not fit into %d bytes", len(digits), length)
+	}
+
+	b := make([]byte, length)
+
+	// fill nibbles from the right
+	for i, n := len(digits)-1, 2*length-1; i >= 0; i, n = i-1, n-1 {
+		d := digits[i] - '0'
+		if n%2 == 0 {
+			d <<= 4
+		}
+
+		b[n/2] |= d
+	}
+
+	return b, nil
+}
+
+func encodeCompressedNumeric(digits string, length int) ([]byte, error) {
+	if err := checkDigits(digits); err != nil {
+		return nil, err
+	}
+
+	if length == 0 {
+		length = (len(digits) + 1) / 2
+	}
+
+	if len(digits) > 2*length {
+		return nil, errors.Errorf("%d digits do not fit into %d bytes", len(digits), length)
+	}
+
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = 0xFF
+	}
+
+	// fill nibbles from the left
+	for i := 0; i < len(digits); i++ {
+		d := digits[i] - '0'
+		if i%2 == 0 {
+			b[i/2] = d<<4 | 0x0F
+		} else {
+			b[i/2] = b[i/2]&0xF0 | d
+		}
+	}
+
+	return b, nil
+}
+
+// Uint returns the value of the BerTLV decoded as big-endian unsigned integer.
+// Returns an error if the value is empty or longer than 8 bytes.
+func (ber BerTLV) Uint() (uint64, error) {
+	if err := ber.checkLength(1, 8); err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for _, b := range ber.Value {
+		v = v<<8 | uint64(b)
+	}
+
+	return v, nil
+}
+
+// Int returns the value of the BerTLV decoded as big-endian signed integer in two's complement.
+// Returns an error if the value is empty or longer than 8 bytes.
+func (ber BerTLV) Int() (int64, error) {
+	if err := ber.checkLength(1, 8); err != nil {
+		return 0, err
+	}
+
+	// sign extension of the first byte
+	v := int64(int8(ber.Value[0]))
+	for _, b := range ber.Value[1:] {
+		v = v<<8 | int64(b)
+	}
+
+	return v, nil
+}
+
+// BCD returns the value of the BerTLV decoded as BCD, like the EMV format 'n', including leading zeros.
+// Returns an error if a nibble is not a decimal digit.
+func (ber BerTLV) BCD() (string, error) {
+	digits := make([]byte, 0, 2*len(ber.Value))
+
+	for i, b := range ber.Value {
+		for _, n := range [2]byte{b >> 4, b & 0x0F} {
+			if n > 9 {
+				return "", errors.Errorf("tag %02X: invalid BCD nibble %X in byte %d", []byte(ber.Tag), n, i)
+			}
+
+			digits = append(digits, '0'+n)
+		}
+	}
+
+	return string(digits), nil
+}
+
+// CompressedNumeric returns the value of the BerTLV decoded as compressed numeric, like the EMV format 'cn'.
+// The digits are left-justified and padded with trailing 'F' nibbles, which are removed.
+// Returns an error if a nibble is neither a decimal digit nor padding, or if a digit follows padding.
+func (ber BerTLV) CompressedNumeric() (string, error) {
+	digits := make([]byte, 0, 2*len(ber.Value))
+	padding := false
+
+	for i, b := range ber.Value {
+		for _, n := range [2]byte{b >> 4, b & 0x0F} {
+			switch {
+			case n == 0x0F:
+				padding = true
+			case n > 9:
+				return "", errors.Errorf("tag %02X: invalid compressed numeric nibble %X in byte %d", []byte(ber.Tag), n, i)
+			case padding:
+				return "", errors.Errorf("tag %02X: digit follows padding in byte %d", []byte(ber.Tag), i)
+			default:
+				digits = append(digits, '0'+n)
+			}
+		}
+	}
+
+	return string(digits), nil
+}
+
+// ASCII returns the value of the BerTLV as string. Returns an error if the value contains bytes that are not
+// printable ASCII characters (0x20 - 0x7E).
+func (ber BerTLV) ASCII() (string, error) {
+	for i, b := range ber.Value {
+		if b < 0x20 || b > 0x7E {
+			return "", errors.Errorf("tag %02X: byte %d is not a printable ASCII character: %02X", []byte(ber.Tag), i, b)
+		}
+	}
+
+	return string(ber.Value), nil
+}
+
+// Bool returns the value of the BerTLV decoded as boolean: '00' is false, all other values are true.
+// Returns an error if the value does not consist of exactly one byte.
+func (ber BerTLV) Bool() (bool, error) {
+	if err := ber.checkLength(1, 1); err != nil {
+		return false, err
+	}
+
+	return ber.Value[0] != 0x00, nil
+}
+
+// Date returns the value of the BerTLV decoded as date in the format YYMMDD encoded as BCD, like the EMV format 'n 6'.
+// Years 00 - 49 are interpreted as 2000 - 2049, years 50 - 99 as 1950 - 1999. The returned time is midnight UTC.
+// Returns an error if the value does not consist of three bytes or is not a valid date.
+func (ber BerTLV) Date() (time.Time, error) {
+	if err := ber.checkLength(3, 3); err != nil {
+		return time.Time{}, err
+	}
+
+	digits, err := ber.BCD()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	year := int(digits[0]-'0')*10 + int(digits[1]-'0')
+	month := int(digits[2]-'0')*10 + int(digits[3]-'0')
+	day := int(digits[4]-'0')*10 + int(digits[5]-'0')
+
+	if year < 50 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+
+	// time.Date normalizes invalid dates like 31 April
+	if date.Month() != time.Month(month) || date.Day() != day {
+		return time.Time{}, errors.Errorf("tag %02X: invalid date %s", []byte(ber.Tag), digits)
+	}
+
+	return date, nil
+}
+
+func (ber BerTLV) checkLength(min int, max int) error {
+	if l := len(ber.Value); l < min || l > max {
+		if min == max {
+			return errors.Errorf("tag %02X: value must have a length of %d, got %d", []byte(ber.Tag), min, l)
+		}
+
+		return errors.Errorf("tag %02X: value must have a length between %d and %d, got %d", []byte(ber.Tag), min, max, l)
+	}
+
+	return nil
+}
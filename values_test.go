@@ -0,0 +1,346 @@
+package bertlv
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestBuilder_AddValues(t *testing.T) {
+	parsed, err := Parse([]byte{0x70, 0x81, 0x03, 0x90, 0x01, 0xFF, 0x91, 0x00})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	tag := NewTwoByteTag(0x9F, 0x02)
+
+	tests := []struct {
+		name        string
+		build       func(bu *Builder)
+		expected    []byte
+		expectError bool
+	}{
+		{
+			name:     "uint, minimal",
+			build:    func(bu *Builder) { bu.AddUint(tag, 0x012345, 0) },
+			expected: []byte{0x9F, 0x02, 0x03, 0x01, 0x23, 0x45},
+		},
+		{
+			name:     "uint, zero",
+			build:    func(bu *Builder) { bu.AddUint(tag, 0, 0) },
+			expected: []byte{0x9F, 0x02, 0x01, 0x00},
+		},
+		{
+			name:     "uint, fixed width",
+			build:    func(bu *Builder) { bu.AddUint(tag, 0x0100, 6) },
+			expected: []byte{0x9F, 0x02, 0x06, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00},
+		},
+		{
+			name:        "Error: uint, does not fit",
+			build:       func(bu *Builder) { bu.AddUint(tag, 0x0100, 1) },
+			expectError: true,
+		},
+		{
+			name:     "int, minimal positive",
+			build:    func(bu *Builder) { bu.AddInt(tag, 128, 0) },
+			expected: []byte{0x9F, 0x02, 0x02, 0x00, 0x80},
+		},
+		{
+			name:     "int, minimal negative",
+			build:    func(bu *Builder) { bu.AddInt(tag, -128, 0) },
+			expected: []byte{0x9F, 0x02, 0x01, 0x80},
+		},
+		{
+			name:     "int, fixed width negative",
+			build:    func(bu *Builder) { bu.AddInt(tag, -2, 3) },
+			expected: []byte{0x9F, 0x02, 0x03, 0xFF, 0xFF, 0xFE},
+		},
+		{
+			name:        "Error: int, does not fit",
+			build:       func(bu *Builder) { bu.AddInt(tag, -129, 1) },
+			expectError: true,
+		},
+		{
+			name:     "string",
+			build:    func(bu *Builder) { bu.AddString(NewTwoByteTag(0x5F, 0x20), "DOE/JOHN") },
+			expected: append([]byte{0x5F, 0x20, 0x08}, "DOE/JOHN"...),
+		},
+		{
+			name:     "bcd, amount with fixed length",
+			build:    func(bu *Builder) { bu.AddBCD(tag, "12345", 6) },
+			expected: []byte{0x9F, 0x02, 0x06, 0x00, 0x00, 0x00, 0x01, 0x23, 0x45},
+		},
+		{
+			name:     "bcd, minimal odd number of digits",
+			build:    func(bu *Builder) { bu.AddBCD(NewTwoByteTag(0x5F, 0x2A), "978", 0) },
+			expected: []byte{0x5F, 0x2A, 0x02, 0x09, 0x78},
+		},
+		{
+			name:        "Error: bcd, invalid digit",
+			build:       func(bu *Builder) { bu.AddBCD(tag, "12A4", 0) },
+			expectError: true,
+		},
+		{
+			name:        "Error: bcd, too long",
+			build:       func(bu *Builder) { bu.AddBCD(tag, "12345", 2) },
+			expectError: true,
+		},
+		{
+			name:     "compressed numeric, padded",
+			build:    func(bu *Builder) { bu.AddCompressedNumeric(NewOneByteTag(0x5A), "4761739001010119", 10) },
+			expected: []byte{0x5A, 0x0A, 0x47, 0x61, 0x73, 0x90, 0x01, 0x01, 0x01, 0x19, 0xFF, 0xFF},
+		},
+		{
+			name:     "compressed numeric, minimal odd number of digits",
+			build:    func(bu *Builder) { bu.AddCompressedNumeric(NewOneByteTag(0x5A), "123", 0) },
+			expected: []byte{0x5A, 0x02, 0x12, 0x3F},
+		},
+		{
+			name:        "Error: compressed numeric, too long",
+			build:       func(bu *Builder) { bu.AddCompressedNumeric(NewOneByteTag(0x5A), "123", 1) },
+			expectError: true,
+		},
+		{
+			name:     "bool",
+			build:    func(bu *Builder) { bu.AddBool(NewOneByteTag(0x01), true).AddBool(NewOneByteTag(0x01), false) },
+			expected: []byte{0x01, 0x01, 0xFF, 0x01, 0x01, 0x00},
+		},
+		{
+			name:     "TLV",
+			build:    func(bu *Builder) { bu.AddTLV(parsed[0]) },
+			expected: []byte{0x70, 0x03, 0x90, 0x01, 0xFF},
+		},
+		{
+			name:     "TLVs",
+			build:    func(bu *Builder) { bu.AddTLVs(parsed) },
+			expected: []byte{0x70, 0x03, 0x90, 0x01, 0xFF, 0x91, 0x00},
+		},
+		{
+			name:        "Error: TLV, invalid tag",
+			build:       func(bu *Builder) { bu.AddTLV(BerTLV{Tag: NewOneByteTag(0x1F)}) },
+			expectError: true,
+		},
+		{
+			name:        "Error: TLVs, value too long",
+			build:       func(bu *Builder) { bu.AddTLVs(BerTLVs{{Tag: NewOneByteTag(0x04), Value: make([]byte, 65536)}}) },
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bu := &Builder{}
+			tc.build(bu)
+
+			err := bu.Err()
+			if err != nil && !tc.expectError {
+				t.Errorf("Expected: no error, got: error(%v)", err.Error())
+
+				return
+			}
+
+			if err == nil && tc.expectError {
+				t.Errorf("Expected: error, got: no error")
+
+				return
+			}
+
+			if received := bu.Bytes(); !bytes.Equal(received, tc.expected) {
+				t.Errorf("Expected: '%X', got: '%X'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_Uint(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    uint64
+		expectError bool
+	}{
+		{name: "one byte", value: []byte{0xFF}, expected: 0xFF},
+		{name: "three byte", value: []byte{0x01, 0x23, 0x45}, expected: 0x012345},
+		{name: "eight byte", value: []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, expected: 0xFFFFFFFFFFFFFFFF},
+		{name: "Error: empty", value: nil, expectError: true},
+		{name: "Error: nine byte", value: make([]byte, 9), expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewOneByteTag(0x9F), Value: tc.value}.Uint()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_Int(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    int64
+		expectError bool
+	}{
+		{name: "positive", value: []byte{0x00, 0x80}, expected: 128},
+		{name: "negative one byte", value: []byte{0x80}, expected: -128},
+		{name: "negative three byte", value: []byte{0xFF, 0xFF, 0xFE}, expected: -2},
+		{name: "Error: empty", value: nil, expectError: true},
+		{name: "Error: nine byte", value: make([]byte, 9), expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewOneByteTag(0x02), Value: tc.value}.Int()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_BCD(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    string
+		expectError bool
+	}{
+		{name: "amount", value: []byte{0x00, 0x00, 0x00, 0x01, 0x23, 0x45}, expected: "000000012345"},
+		{name: "empty", value: nil, expected: ""},
+		{name: "Error: invalid nibble", value: []byte{0x12, 0x3A}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewTwoByteTag(0x9F, 0x02), Value: tc.value}.BCD()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_CompressedNumeric(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    string
+		expectError bool
+	}{
+		{name: "padded", value: []byte{0x47, 0x61, 0x73, 0x90, 0x01, 0x01, 0x01, 0x19, 0xFF, 0xFF}, expected: "4761739001010119"},
+		{name: "odd number of digits", value: []byte{0x12, 0x3F}, expected: "123"},
+		{name: "no padding", value: []byte{0x12, 0x34}, expected: "1234"},
+		{name: "Error: invalid nibble", value: []byte{0x12, 0xAF}, expectError: true},
+		{name: "Error: digit after padding", value: []byte{0x1F, 0x2F}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewOneByteTag(0x5A), Value: tc.value}.CompressedNumeric()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_ASCII(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    string
+		expectError bool
+	}{
+		{name: "printable", value: []byte("DOE/JOHN "), expected: "DOE/JOHN "},
+		{name: "Error: control character", value: []byte{0x41, 0x0A}, expectError: true},
+		{name: "Error: non ASCII", value: []byte{0x41, 0xC4}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewTwoByteTag(0x5F, 0x20), Value: tc.value}.ASCII()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_Bool(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    bool
+		expectError bool
+	}{
+		{name: "true", value: []byte{0xFF}, expected: true},
+		{name: "true, non DER", value: []byte{0x01}, expected: true},
+		{name: "false", value: []byte{0x00}, expected: false},
+		{name: "Error: empty", value: nil, expectError: true},
+		{name: "Error: two byte", value: []byte{0x00, 0x00}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewOneByteTag(0x01), Value: tc.value}.Bool()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if received != tc.expected {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
+
+func TestBerTLV_Date(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       []byte
+		expected    time.Time
+		expectError bool
+	}{
+		{name: "2000s", value: []byte{0x25, 0x12, 0x31}, expected: time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{name: "1900s", value: []byte{0x99, 0x02, 0x28}, expected: time.Date(1999, time.February, 28, 0, 0, 0, 0, time.UTC)},
+		{name: "Error: invalid day", value: []byte{0x25, 0x04, 0x31}, expectError: true},
+		{name: "Error: invalid month", value: []byte{0x25, 0x13, 0x01}, expectError: true},
+		{name: "Error: invalid nibble", value: []byte{0x25, 0x1A, 0x01}, expectError: true},
+		{name: "Error: wrong length", value: []byte{0x25, 0x12}, expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received, err := BerTLV{Tag: NewTwoByteTag(0x5F, 0x24), Value: tc.value}.Date()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected: error %v, got: '%v'", tc.expectError, err)
+			}
+
+			if !received.Equal(tc.expected) {
+				t.Errorf("Expected: '%v', got: '%v'", tc.expected, received)
+			}
+		})
+	}
+}
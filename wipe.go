@@ -0,0 +1,59 @@
+package bertlv
+
+// Wipe overwrites the Value and the recorded header of the BerTLV and of all its child and speculatively decoded
+// objects with zeros and resets the BerTLV to its zero value. Use it to erase key material, PIN blocks or track
+// data after use.
+//
+// Parsed objects share memory with the input they were parsed from, so wiping them also zeros the respective bytes
+// of the input, and wiping a child object zeros the corresponding bytes of the Value of its parent. Copies of the
+// BerTLV like the objects returned by Children share this memory, clones do not and must be wiped separately.
+// Padding and other input bytes that are not part of any object are not wiped, see ParseOptions.CopyInto.
+func (ber *BerTLV) Wipe() {
+	wipe(ber.Value)
+	wipe(ber.header)
+	BerTLVs(ber.children).Wipe()
+	BerTLVs(ber.speculative).Wipe()
+
+	*ber = BerTLV{}
+}
+
+// Wipe calls BerTLV.Wipe for all BerTLV of BerTLVs.
+func (t BerTLVs) Wipe() {
+	for i := range t {
+		t[i].Wipe()
+	}
+}
+
+// Wipe overwrites the whole buffer of the Builder, including unused capacity, with zeros and resets the Builder
+// to its zero value. Slices returned by Bytes and BerTLV objects returned by BuildBerTLVs refer to the buffer and
+// are wiped as well. Values that the Builder encodes into temporary buffers, e.g. in AddInteger, AddBCD, AddString
+// or when strings are segmented, are wiped as soon as they have been added, and Grow wipes the buffer it replaces.
+//
+// Wipe can not reach the following copies:
+//
+//   - buffers that were replaced when the Builder had to grow while adding a value; call Grow with the expected
+//     total length before adding sensitive data
+//   - the arguments of the Builder methods, like the slice passed to AddBytes or the *big.Int passed to AddInteger,
+//     which are owned by the caller; strings are immutable and can not be wiped at all
+//   - recorded errors, which may contain the value that could not be added, e.g. in AddUint
+func (bu *Builder) Wipe() {
+	wipe(bu.bytes[:cap(bu.bytes)])
+
+	*bu = Builder{}
+}
+
+// addTemporary adds the given tag with a value that the Builder encoded into the temporary buffer v like addBytes
+// and wipes v afterwards.
+func (bu *Builder) addTemporary(method string, tag BerTag, v []byte) *Builder {
+	bu.addBytes(method, tag, v)
+	wipe(v)
+
+	return bu
+}
+
+// wipe overwrites b with zeros.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
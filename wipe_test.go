@@ -0,0 +1,154 @@
+package bertlv
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBerTLVs_Wipe(t *testing.T) {
+	input := []byte{0xE1, 0x08, 0x5A, 0x02, 0x12, 0x34, 0xFF, 0x57, 0x01, 0xD1, 0xFF, 0x9F, 0x01, 0x01, 0xAA}
+	buf := make([]byte, 32)
+
+	tlvs, err := ParseWithOptions(input, ParseOptions{Padding: []byte{0xFF}, CopyInto: buf})
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	clone := tlvs.Clone()
+	child := tlvs[0].Children(nil)[1]
+
+	tlvs.Wipe()
+
+	for i, tlv := range tlvs {
+		if tlv.Tag != nil || tlv.Value != nil || tlv.children != nil || tlv.header != nil {
+			t.Errorf("Expected: BerTLV %d reset, got: '%v'", i, tlv)
+		}
+	}
+
+	// all bytes of objects are wiped, only padding between top level objects is left in the buffer
+	expected := make([]byte, 32)
+	expected[10] = 0xFF
+	if !bytes.Equal(buf, expected) {
+		t.Errorf("Expected: '%X', got: '%X'", expected, buf)
+	}
+
+	// copies share the memory, clones do not
+	if !bytes.Equal(child.Value, []byte{0x00}) {
+		t.Errorf("Expected: wiped value, got: '%X'", child.Value)
+	}
+
+	if !bytes.Equal(clone.Bytes(), []byte{0xE1, 0x08, 0x5A, 0x02, 0x12, 0x34, 0xFF, 0x57, 0x01, 0xD1, 0x9F, 0x01, 0x01, 0xAA}) {
+		t.Errorf("Expected: unchanged clone, got: '%X'", clone.Bytes())
+	}
+
+	// the input is not changed when parsing from a copy
+	if input[2] != 0x5A {
+		t.Errorf("Expected: unchanged input, got: '%X'", input)
+	}
+}
+
+func TestBerTLV_Wipe(t *testing.T) {
+	input := []byte{0x70, 0x06, 0x04, 0x04, 0x5A, 0x02, 0x12, 0x34}
+
+	parsed, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	tlvs := parsed.DecodeHeuristic(DefaultHeuristicOptions)
+	if len(tlvs[0].children[0].speculative) == 0 {
+		t.Fatalf("Expected: speculative objects, got: none")
+	}
+
+	// speculative objects refer to the value of the primitive object
+	speculative := tlvs[0].children[0].speculative[0].Value
+
+	tlvs[0].Wipe()
+
+	if !bytes.Equal(input, make([]byte, len(input))) {
+		t.Errorf("Expected: wiped input, got: '%X'", input)
+	}
+
+	if !bytes.Equal(speculative, []byte{0x00, 0x00}) {
+		t.Errorf("Expected: wiped speculative value, got: '%X'", speculative)
+	}
+
+	var empty BerTLV
+	empty.Wipe()
+}
+
+func TestBuilder_Wipe(t *testing.T) {
+	bu := &Builder{}
+	bu.Grow(64).AddBytes(NewOneByteTag(0xDF), []byte{0x01, 0x02, 0x03, 0x04}).Begin(NewOneByteTag(0x70)).AddByte(NewOneByteTag(0x80), 0xFF)
+
+	b := bu.Bytes()
+
+	bu.Wipe()
+
+	if !bytes.Equal(b[:cap(b)], make([]byte, cap(b))) {
+		t.Errorf("Expected: wiped buffer, got: '%X'", b[:cap(b)])
+	}
+
+	if bu.Bytes() != nil || bu.Err() != nil {
+		t.Errorf("Expected: reset Builder, got: '%X', error(%v)", bu.Bytes(), bu.Err())
+	}
+
+	if received := bu.AddByte(NewOneByteTag(0x80), 0x01).Bytes(); !bytes.Equal(received, []byte{0x80, 0x01, 0x01}) {
+		t.Errorf("Expected: '800101', got: '%X'", received)
+	}
+
+	(&Builder{}).Wipe()
+
+	// the buffer replaced by Grow is wiped
+	bu = (&Builder{}).AddBytes(NewOneByteTag(0x5A), []byte{0x01, 0x02, 0x03, 0x04})
+	replaced := bu.Bytes()
+
+	if received := bu.Grow(64).Bytes(); !bytes.Equal(received, []byte{0x5A, 0x04, 0x01, 0x02, 0x03, 0x04}) {
+		t.Errorf("Expected: '5A0401020304', got: '%X'", received)
+	}
+
+	if !bytes.Equal(replaced, make([]byte, len(replaced))) {
+		t.Errorf("Expected: wiped buffer, got: '%X'", replaced)
+	}
+}
+
+func TestBuilder_addTemporary(t *testing.T) {
+	v := []byte{0x01, 0x02}
+
+	if received := (&Builder{}).addTemporary("AddBCD", NewOneByteTag(0x5A), v).Bytes(); !bytes.Equal(received, []byte{0x5A, 0x02, 0x01, 0x02}) {
+		t.Errorf("Expected: '5A020102', got: '%X'", received)
+	}
+
+	if !bytes.Equal(v, []byte{0x00, 0x00}) {
+		t.Errorf("Expected: wiped value, got: '%X'", v)
+	}
+}
+
+func TestParseWithOptions_CopyInto(t *testing.T) {
+	input := []byte{0x5A, 0x01, 0x12}
+	opts := ParseOptions{CopyInto: make([]byte, 2)}
+
+	if _, err := ParseWithOptions(input, opts); err == nil {
+		t.Errorf("Expected: error, got: nil")
+	}
+
+	if _, _, _, err := ParseFirstWithOptions(input, opts); err == nil {
+		t.Errorf("Expected: error, got: nil")
+	}
+
+	if _, problems := ParseLenient(input, opts); len(problems) != 1 {
+		t.Errorf("Expected: 1 problem, got: %v", problems)
+	}
+
+	opts.CopyInto = make([]byte, 3)
+
+	received, _, rest, err := ParseFirstWithOptions(input, opts)
+	if err != nil {
+		t.Fatalf("Expected: no error, got: error(%v)", err.Error())
+	}
+
+	received.Value[0] = 0x34
+	if input[2] != 0x12 || opts.CopyInto[2] != 0x34 || len(rest) != 0 {
+		t.Errorf("Expected: value in buffer, got: input '%X', buffer '%X'", input, opts.CopyInto)
+	}
+}